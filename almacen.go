@@ -0,0 +1,210 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrEmailDuplicado lo devuelve Crear cuando el backend detecta por sí
+// mismo que el email ya existe (índice único en SQL); los handlers lo
+// traducen al 409 habitual.
+var ErrEmailDuplicado = errors.New("ya existe un usuario con ese email")
+
+// Almacenamiento abstrae el backend de usuarios para que los handlers
+// HTTP no dependan de dónde viven los datos y se pueda enchufar otra
+// implementación (archivo, SQL) sin tocarlos. Las operaciones son
+// atómicas individualmente; las invariantes que abarcan varias llamadas
+// (unicidad de email, lotes) las serializa el mutex del servidor.
+type Almacenamiento interface {
+	// Crear asigna el siguiente ID, guarda el usuario y lo devuelve
+	// completo. Devuelve ErrEmailDuplicado si el backend rechaza el
+	// email por repetido.
+	Crear(usuario Usuario) (Usuario, error)
+
+	// ObtenerTodos devuelve una copia de todos los usuarios en orden de
+	// inserción.
+	ObtenerTodos() ([]Usuario, error)
+
+	// ObtenerPorID devuelve el usuario y si existe.
+	ObtenerPorID(id int) (Usuario, bool, error)
+
+	// Actualizar reemplaza el usuario que tenga el mismo ID. Devuelve
+	// false si no existe.
+	Actualizar(usuario Usuario) (bool, error)
+
+	// Eliminar borra el usuario por ID conservando el orden del resto.
+	// Devuelve false si no existe.
+	Eliminar(id int) (bool, error)
+
+	// Contar devuelve el número de usuarios guardados.
+	Contar() (int, error)
+
+	// Reemplazar repone la lista completa (típicamente al cargar desde
+	// persistencia) y reconstruye el contador de IDs.
+	Reemplazar(usuarios []Usuario) error
+
+	// Purgar elimina todos los usuarios y reinicia el contador de IDs
+	// desde 1 (a diferencia de Reemplazar, que nunca lo retrocede).
+	// Devuelve cuántos usuarios había. Pensado para entornos de prueba.
+	Purgar() (int, error)
+
+	// VerificarEscritura comprueba que el almacén acepta escrituras; lo
+	// usan las verificaciones de salud.
+	VerificarEscritura() error
+}
+
+// abrirAlmacen construye el backend de usuarios que pide la
+// configuración (campo Almacenamiento, o RutaBaseDatos sola como
+// selección implícita de sqlite). Devuelve además la función de cierre
+// del backend, nil para los que no mantienen recursos abiertos.
+func abrirAlmacen(config *Configuracion) (Almacenamiento, func() error, error) {
+	backend := strings.ToLower(strings.TrimSpace(config.Almacenamiento))
+	if backend == "" && config.RutaBaseDatos != "" {
+		backend = "sqlite"
+	}
+	switch backend {
+	case "", "memoria":
+		return nuevoAlmacenEnMemoria(), nil, nil
+	case "sqlite":
+		if config.RutaBaseDatos == "" {
+			return nil, nil, errors.New("el almacenamiento sqlite requiere ruta_base_datos")
+		}
+		sqlite, err := nuevoAlmacenSQLite(config.RutaBaseDatos)
+		if err != nil {
+			return nil, nil, fmt.Errorf("no se pudo abrir la base de datos: %w", err)
+		}
+		return sqlite, sqlite.Cerrar, nil
+	case "postgres":
+		if config.DSNPostgres == "" {
+			return nil, nil, errors.New("el almacenamiento postgres requiere dsn_postgres")
+		}
+		postgres, err := nuevoAlmacenPostgres(config)
+		if err != nil {
+			return nil, nil, err
+		}
+		return postgres, postgres.Cerrar, nil
+	default:
+		return nil, nil, fmt.Errorf("almacenamiento %q desconocido", config.Almacenamiento)
+	}
+}
+
+// almacenEnMemoria es la implementación por defecto: un slice protegido
+// por RWMutex con IDs secuenciales. Sus operaciones no pueden fallar,
+// así que todos los errores devueltos son nil.
+type almacenEnMemoria struct {
+	mutex       sync.RWMutex
+	usuarios    []Usuario
+	siguienteID int
+}
+
+func nuevoAlmacenEnMemoria() *almacenEnMemoria {
+	return &almacenEnMemoria{usuarios: []Usuario{}, siguienteID: 1}
+}
+
+func (a *almacenEnMemoria) Crear(usuario Usuario) (Usuario, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	usuario.ID = a.siguienteID
+	a.siguienteID++
+	a.usuarios = append(a.usuarios, usuario)
+	return usuario, nil
+}
+
+func (a *almacenEnMemoria) ObtenerTodos() ([]Usuario, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	copia := make([]Usuario, len(a.usuarios))
+	copy(copia, a.usuarios)
+	return copia, nil
+}
+
+func (a *almacenEnMemoria) ObtenerPorID(id int) (Usuario, bool, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+
+	for _, usuario := range a.usuarios {
+		if usuario.ID == id {
+			return usuario, true, nil
+		}
+	}
+	return Usuario{}, false, nil
+}
+
+func (a *almacenEnMemoria) Actualizar(usuario Usuario) (bool, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for i := range a.usuarios {
+		if a.usuarios[i].ID == usuario.ID {
+			a.usuarios[i] = usuario
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (a *almacenEnMemoria) Eliminar(id int) (bool, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	for i := range a.usuarios {
+		if a.usuarios[i].ID == id {
+			a.usuarios = append(a.usuarios[:i], a.usuarios[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (a *almacenEnMemoria) Contar() (int, error) {
+	a.mutex.RLock()
+	defer a.mutex.RUnlock()
+	return len(a.usuarios), nil
+}
+
+func (a *almacenEnMemoria) Reemplazar(usuarios []Usuario) error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.usuarios = make([]Usuario, len(usuarios))
+	copy(a.usuarios, usuarios)
+
+	// El contador nunca retrocede para no reutilizar IDs ya entregados.
+	siguiente := 1
+	for _, usuario := range usuarios {
+		if usuario.ID >= siguiente {
+			siguiente = usuario.ID + 1
+		}
+	}
+	if siguiente > a.siguienteID {
+		a.siguienteID = siguiente
+	}
+	return nil
+}
+
+func (a *almacenEnMemoria) Purgar() (int, error) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	eliminados := len(a.usuarios)
+	a.usuarios = []Usuario{}
+	a.siguienteID = 1
+	return eliminados, nil
+}
+
+func (a *almacenEnMemoria) VerificarEscritura() error {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	antes := len(a.usuarios)
+	a.usuarios = append(a.usuarios, Usuario{ID: -1, Nombre: "__verificacion__"})
+	if len(a.usuarios) != antes+1 {
+		return errors.New("la escritura de prueba no se reflejó")
+	}
+	a.usuarios = a.usuarios[:antes]
+	return nil
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	_ "modernc.org/sqlite"
+)
+
+// esquemaUsuarios crea la tabla al arrancar si no existe. El índice único
+// sobre el email en minúsculas hace que la base de datos misma rechace
+// duplicados aunque varias instancias compartan el archivo.
+const esquemaUsuarios = `
+CREATE TABLE IF NOT EXISTS usuarios (
+	id          INTEGER PRIMARY KEY AUTOINCREMENT,
+	nombre      TEXT NOT NULL,
+	email       TEXT NOT NULL,
+	creado      TEXT NOT NULL DEFAULT '',
+	actualizado TEXT NOT NULL DEFAULT '',
+	metadatos   TEXT NOT NULL DEFAULT ''
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_usuarios_email ON usuarios (lower(email));
+`
+
+// almacenSQLite implementa Almacenamiento sobre un archivo SQLite vía
+// database/sql. Todas las consultas van parametrizadas; la concurrencia
+// la maneja el propio driver.
+type almacenSQLite struct {
+	bd *sql.DB
+}
+
+// nuevoAlmacenSQLite abre (o crea) la base de datos en la ruta dada y
+// asegura el esquema. Con ":memory:" la base vive solo en memoria, útil
+// en pruebas.
+func nuevoAlmacenSQLite(ruta string) (*almacenSQLite, error) {
+	bd, err := sql.Open("sqlite", ruta)
+	if err != nil {
+		return nil, fmt.Errorf("error al abrir la base de datos %s: %w", ruta, err)
+	}
+	// El driver es puro Go y serializa mal las escrituras concurrentes
+	// sobre varias conexiones; una sola conexión evita "database is
+	// locked" sin coste apreciable para este servicio.
+	bd.SetMaxOpenConns(1)
+
+	if _, err := bd.Exec(esquemaUsuarios); err != nil {
+		bd.Close()
+		return nil, fmt.Errorf("error al crear el esquema: %w", err)
+	}
+	// Migración para bases creadas antes de la columna de metadatos; en
+	// las nuevas el ALTER falla por columna duplicada y se ignora.
+	if _, err := bd.Exec("ALTER TABLE usuarios ADD COLUMN metadatos TEXT NOT NULL DEFAULT ''"); err != nil &&
+		!strings.Contains(err.Error(), "duplicate column name") {
+		bd.Close()
+		return nil, fmt.Errorf("error al migrar el esquema: %w", err)
+	}
+	return &almacenSQLite{bd: bd}, nil
+}
+
+// codificarMetadatos serializa el mapa de metadatos como JSON para la
+// columna de texto; un mapa vacío se guarda como cadena vacía.
+func codificarMetadatos(metadatos map[string]string) (string, error) {
+	if len(metadatos) == 0 {
+		return "", nil
+	}
+	datos, err := json.Marshal(metadatos)
+	if err != nil {
+		return "", fmt.Errorf("error al serializar metadatos: %w", err)
+	}
+	return string(datos), nil
+}
+
+// decodificarMetadatos reconstruye el mapa desde la columna de texto.
+func decodificarMetadatos(texto string) (map[string]string, error) {
+	if texto == "" {
+		return nil, nil
+	}
+	var metadatos map[string]string
+	if err := json.Unmarshal([]byte(texto), &metadatos); err != nil {
+		return nil, fmt.Errorf("error al decodificar metadatos: %w", err)
+	}
+	return metadatos, nil
+}
+
+// Cerrar libera la conexión con la base de datos.
+func (a *almacenSQLite) Cerrar() error {
+	return a.bd.Close()
+}
+
+// esViolacionDeUnicidad detecta el rechazo del índice único de email.
+func esViolacionDeUnicidad(err error) bool {
+	return err != nil && strings.Contains(err.Error(), "UNIQUE constraint failed")
+}
+
+func (a *almacenSQLite) Crear(usuario Usuario) (Usuario, error) {
+	metadatos, err := codificarMetadatos(usuario.Metadatos)
+	if err != nil {
+		return Usuario{}, err
+	}
+	resultado, err := a.bd.Exec(
+		"INSERT INTO usuarios (nombre, email, creado, actualizado, metadatos) VALUES (?, ?, ?, ?, ?)",
+		usuario.Nombre, usuario.Email, usuario.Creado, usuario.Actualizado, metadatos,
+	)
+	if esViolacionDeUnicidad(err) {
+		return Usuario{}, ErrEmailDuplicado
+	}
+	if err != nil {
+		return Usuario{}, fmt.Errorf("error al insertar usuario: %w", err)
+	}
+
+	id, err := resultado.LastInsertId()
+	if err != nil {
+		return Usuario{}, fmt.Errorf("error al obtener el ID asignado: %w", err)
+	}
+	usuario.ID = int(id)
+	return usuario, nil
+}
+
+func (a *almacenSQLite) ObtenerTodos() ([]Usuario, error) {
+	filas, err := a.bd.Query("SELECT id, nombre, email, creado, actualizado, metadatos FROM usuarios ORDER BY id")
+	if err != nil {
+		return nil, fmt.Errorf("error al consultar usuarios: %w", err)
+	}
+	defer filas.Close()
+
+	usuarios := []Usuario{}
+	for filas.Next() {
+		var usuario Usuario
+		var metadatos string
+		if err := filas.Scan(&usuario.ID, &usuario.Nombre, &usuario.Email, &usuario.Creado, &usuario.Actualizado, &metadatos); err != nil {
+			return nil, fmt.Errorf("error al leer fila de usuario: %w", err)
+		}
+		if usuario.Metadatos, err = decodificarMetadatos(metadatos); err != nil {
+			return nil, err
+		}
+		usuarios = append(usuarios, usuario)
+	}
+	return usuarios, filas.Err()
+}
+
+func (a *almacenSQLite) ObtenerPorID(id int) (Usuario, bool, error) {
+	var usuario Usuario
+	var metadatos string
+	err := a.bd.QueryRow(
+		"SELECT id, nombre, email, creado, actualizado, metadatos FROM usuarios WHERE id = ?", id,
+	).Scan(&usuario.ID, &usuario.Nombre, &usuario.Email, &usuario.Creado, &usuario.Actualizado, &metadatos)
+	if errors.Is(err, sql.ErrNoRows) {
+		return Usuario{}, false, nil
+	}
+	if err != nil {
+		return Usuario{}, false, fmt.Errorf("error al consultar usuario %d: %w", id, err)
+	}
+	if usuario.Metadatos, err = decodificarMetadatos(metadatos); err != nil {
+		return Usuario{}, false, err
+	}
+	return usuario, true, nil
+}
+
+func (a *almacenSQLite) Actualizar(usuario Usuario) (bool, error) {
+	metadatos, err := codificarMetadatos(usuario.Metadatos)
+	if err != nil {
+		return false, err
+	}
+	resultado, err := a.bd.Exec(
+		"UPDATE usuarios SET nombre = ?, email = ?, creado = ?, actualizado = ?, metadatos = ? WHERE id = ?",
+		usuario.Nombre, usuario.Email, usuario.Creado, usuario.Actualizado, metadatos, usuario.ID,
+	)
+	if esViolacionDeUnicidad(err) {
+		return false, ErrEmailDuplicado
+	}
+	if err != nil {
+		return false, fmt.Errorf("error al actualizar usuario %d: %w", usuario.ID, err)
+	}
+	afectadas, err := resultado.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error al contar filas actualizadas: %w", err)
+	}
+	return afectadas > 0, nil
+}
+
+func (a *almacenSQLite) Eliminar(id int) (bool, error) {
+	resultado, err := a.bd.Exec("DELETE FROM usuarios WHERE id = ?", id)
+	if err != nil {
+		return false, fmt.Errorf("error al eliminar usuario %d: %w", id, err)
+	}
+	afectadas, err := resultado.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("error al contar filas eliminadas: %w", err)
+	}
+	return afectadas > 0, nil
+}
+
+func (a *almacenSQLite) Contar() (int, error) {
+	var total int
+	if err := a.bd.QueryRow("SELECT COUNT(*) FROM usuarios").Scan(&total); err != nil {
+		return 0, fmt.Errorf("error al contar usuarios: %w", err)
+	}
+	return total, nil
+}
+
+func (a *almacenSQLite) Reemplazar(usuarios []Usuario) error {
+	tx, err := a.bd.Begin()
+	if err != nil {
+		return fmt.Errorf("error al abrir transacción: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec("DELETE FROM usuarios"); err != nil {
+		return fmt.Errorf("error al vaciar la tabla: %w", err)
+	}
+	for _, usuario := range usuarios {
+		metadatos, err := codificarMetadatos(usuario.Metadatos)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(
+			"INSERT INTO usuarios (id, nombre, email, creado, actualizado, metadatos) VALUES (?, ?, ?, ?, ?, ?)",
+			usuario.ID, usuario.Nombre, usuario.Email, usuario.Creado, usuario.Actualizado, metadatos,
+		); err != nil {
+			return fmt.Errorf("error al insertar usuario %d: %w", usuario.ID, err)
+		}
+	}
+	return tx.Commit()
+}
+
+func (a *almacenSQLite) Purgar() (int, error) {
+	tx, err := a.bd.Begin()
+	if err != nil {
+		return 0, fmt.Errorf("error al abrir transacción: %w", err)
+	}
+	defer tx.Rollback()
+
+	resultado, err := tx.Exec("DELETE FROM usuarios")
+	if err != nil {
+		return 0, fmt.Errorf("error al vaciar la tabla: %w", err)
+	}
+	eliminados, err := resultado.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf("error al contar filas eliminadas: %w", err)
+	}
+	// Reiniciar la secuencia AUTOINCREMENT para que los IDs vuelvan a
+	// empezar en 1; la fila puede no existir si nunca se insertó nada.
+	if _, err := tx.Exec("DELETE FROM sqlite_sequence WHERE name = 'usuarios'"); err != nil &&
+		!strings.Contains(err.Error(), "no such table") {
+		return 0, fmt.Errorf("error al reiniciar la secuencia de IDs: %w", err)
+	}
+	return int(eliminados), tx.Commit()
+}
+
+func (a *almacenSQLite) VerificarEscritura() error {
+	// Una inserción real dentro de una transacción que siempre se
+	// revierte: comprueba que el archivo acepta escrituras sin dejar
+	// rastro en la tabla.
+	tx, err := a.bd.Begin()
+	if err != nil {
+		return fmt.Errorf("error al abrir transacción de prueba: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(
+		"INSERT INTO usuarios (nombre, email) VALUES ('__verificacion__', '__verificacion__')",
+	); err != nil {
+		return fmt.Errorf("la escritura de prueba falló: %w", err)
+	}
+	return nil
+}
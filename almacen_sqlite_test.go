@@ -0,0 +1,167 @@
+package main
+
+import (
+	"errors"
+	"testing"
+)
+
+// almacenSQLiteDePrueba abre una base en memoria que muere con la prueba.
+func almacenSQLiteDePrueba(t *testing.T) *almacenSQLite {
+	t.Helper()
+	almacen, err := nuevoAlmacenSQLite(":memory:")
+	if err != nil {
+		t.Fatalf("no se pudo abrir la base en memoria: %v", err)
+	}
+	t.Cleanup(func() { almacen.Cerrar() })
+	return almacen
+}
+
+func TestAlmacenSQLiteCrearYLeer(t *testing.T) {
+	almacen := almacenSQLiteDePrueba(t)
+
+	ana := crearEnAlmacen(t, almacen, "Ana", "ana@ejemplo.com")
+	juan := crearEnAlmacen(t, almacen, "Juan", "juan@ejemplo.com")
+	if ana.ID != 1 || juan.ID != 2 {
+		t.Fatalf("los IDs deberían ser secuenciales desde 1: %d, %d", ana.ID, juan.ID)
+	}
+
+	usuario, existe, err := almacen.ObtenerPorID(ana.ID)
+	if err != nil || !existe || usuario.Email != "ana@ejemplo.com" {
+		t.Fatalf("el usuario creado debería encontrarse: %+v, %v, %v", usuario, existe, err)
+	}
+	if _, existe, _ := almacen.ObtenerPorID(999); existe {
+		t.Fatal("un ID inexistente no debería encontrarse")
+	}
+
+	todos, err := almacen.ObtenerTodos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(todos) != 2 || todos[0].ID != 1 || todos[1].ID != 2 {
+		t.Fatalf("la lista debería venir completa y en orden de ID: %+v", todos)
+	}
+}
+
+func TestAlmacenSQLiteRechazaEmailDuplicado(t *testing.T) {
+	almacen := almacenSQLiteDePrueba(t)
+	crearEnAlmacen(t, almacen, "Ana", "ana@ejemplo.com")
+
+	// El índice es sobre lower(email): el duplicado con otra caja también
+	// debe rechazarse.
+	if _, err := almacen.Crear(Usuario{Nombre: "Otra", Email: "ANA@ejemplo.com"}); !errors.Is(err, ErrEmailDuplicado) {
+		t.Fatalf("un email repetido debería devolver ErrEmailDuplicado, devolvió %v", err)
+	}
+	if total := contarEnAlmacen(t, almacen); total != 1 {
+		t.Fatalf("el intento rechazado no debería insertar nada: %d usuarios", total)
+	}
+}
+
+func TestAlmacenSQLiteEliminar(t *testing.T) {
+	almacen := almacenSQLiteDePrueba(t)
+	ana := crearEnAlmacen(t, almacen, "Ana", "ana@ejemplo.com")
+	crearEnAlmacen(t, almacen, "Juan", "juan@ejemplo.com")
+
+	if eliminado, err := almacen.Eliminar(ana.ID); err != nil || !eliminado {
+		t.Fatalf("eliminar un usuario existente debería devolver true: %v, %v", eliminado, err)
+	}
+	if eliminado, _ := almacen.Eliminar(ana.ID); eliminado {
+		t.Fatal("eliminar dos veces el mismo ID debería devolver false")
+	}
+	if total := contarEnAlmacen(t, almacen); total != 1 {
+		t.Fatalf("debería quedar 1 usuario, quedan %d", total)
+	}
+
+	// AUTOINCREMENT garantiza que el ID borrado no se reutiliza.
+	if nuevo := crearEnAlmacen(t, almacen, "Lucía", "lucia@ejemplo.com"); nuevo.ID == ana.ID {
+		t.Fatalf("el ID %d del usuario borrado no debería reutilizarse", nuevo.ID)
+	}
+}
+
+func TestAlmacenSQLiteActualizar(t *testing.T) {
+	almacen := almacenSQLiteDePrueba(t)
+	usuario := crearEnAlmacen(t, almacen, "Ana", "ana@ejemplo.com")
+
+	usuario.Nombre = "Ana María"
+	if existe, err := almacen.Actualizar(usuario); err != nil || !existe {
+		t.Fatalf("actualizar un usuario existente debería devolver true: %v, %v", existe, err)
+	}
+	if guardado, _, _ := almacen.ObtenerPorID(usuario.ID); guardado.Nombre != "Ana María" {
+		t.Fatalf("el cambio no se guardó: %q", guardado.Nombre)
+	}
+	if existe, _ := almacen.Actualizar(Usuario{ID: 999, Email: "nadie@ejemplo.com"}); existe {
+		t.Fatal("actualizar un usuario inexistente debería devolver false")
+	}
+}
+
+func TestAlmacenSQLiteVerificarEscrituraSinResiduos(t *testing.T) {
+	almacen := almacenSQLiteDePrueba(t)
+	crearEnAlmacen(t, almacen, "Ana", "ana@ejemplo.com")
+
+	if err := almacen.VerificarEscritura(); err != nil {
+		t.Fatalf("la verificación de escritura debería pasar: %v", err)
+	}
+	if total := contarEnAlmacen(t, almacen); total != 1 {
+		t.Fatalf("la verificación dejó registros residuales: %d usuarios", total)
+	}
+}
+
+func TestAlmacenSQLitePersisteMetadatos(t *testing.T) {
+	almacen := almacenSQLiteDePrueba(t)
+
+	creado, err := almacen.Crear(Usuario{
+		Nombre:    "Ana",
+		Email:     "ana@ejemplo.com",
+		Metadatos: map[string]string{"telefono": "555-1234", "departamento": "ventas"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	leido, existe, err := almacen.ObtenerPorID(creado.ID)
+	if err != nil || !existe {
+		t.Fatalf("el usuario debería encontrarse: %v, %v", existe, err)
+	}
+	if leido.Metadatos["telefono"] != "555-1234" || leido.Metadatos["departamento"] != "ventas" {
+		t.Fatalf("los metadatos deberían sobrevivir el viaje por SQLite: %+v", leido.Metadatos)
+	}
+
+	sinMetadatos := crearEnAlmacen(t, almacen, "Juan", "juan@ejemplo.com")
+	if leido, _, _ := almacen.ObtenerPorID(sinMetadatos.ID); leido.Metadatos != nil {
+		t.Fatalf("un usuario sin metadatos debería volver con el mapa nil: %+v", leido.Metadatos)
+	}
+}
+
+func TestServidorConAlmacenSQLite(t *testing.T) {
+	servidor := NuevoServidorConAlmacen(&Configuracion{}, almacenSQLiteDePrueba(t))
+
+	creado := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	if creado.ID != 1 {
+		t.Fatalf("el primer usuario debería recibir el ID 1, recibió %d", creado.ID)
+	}
+	if total := contarEnAlmacen(t, servidor.almacen); total != 1 {
+		t.Fatalf("el usuario debería quedar en la base: %d", total)
+	}
+}
+
+func TestAlmacenSQLitePurgar(t *testing.T) {
+	almacen := almacenSQLiteDePrueba(t)
+	crearEnAlmacen(t, almacen, "Ana", "ana@ejemplo.com")
+	crearEnAlmacen(t, almacen, "Benito", "benito@ejemplo.com")
+
+	eliminados, err := almacen.Purgar()
+	if err != nil {
+		t.Fatalf("Purgar devolvió error: %v", err)
+	}
+	if eliminados != 2 {
+		t.Fatalf("se esperaban 2 eliminados, fueron %d", eliminados)
+	}
+	if total := contarEnAlmacen(t, almacen); total != 0 {
+		t.Fatalf("la tabla debería quedar vacía, quedan %d", total)
+	}
+
+	// A diferencia de Eliminar, la purga también reinicia la secuencia.
+	nuevo := crearEnAlmacen(t, almacen, "Carla", "carla@ejemplo.com")
+	if nuevo.ID != 1 {
+		t.Fatalf("tras la purga el primer ID debería ser 1, fue %d", nuevo.ID)
+	}
+}
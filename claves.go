@@ -0,0 +1,186 @@
+package main
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ClaveAPI es una credencial de acceso con un ámbito opcional. La clave en
+// claro nunca se serializa hacia los clientes.
+type ClaveAPI struct {
+	ID       int    `json:"id"`
+	Clave    string `json:"-"`
+	Ambito   string `json:"ambito,omitempty"`
+	Revocada bool   `json:"revocada"`
+}
+
+// registroClaves mantiene las API keys activas de forma thread-safe, de
+// modo que una clave comprometida pueda revocarse en caliente.
+type registroClaves struct {
+	mutex       sync.RWMutex
+	claves      []ClaveAPI
+	siguienteID int
+}
+
+func nuevoRegistroClaves() *registroClaves {
+	return &registroClaves{siguienteID: 1}
+}
+
+// agregar registra una clave nueva y devuelve su entrada con ID asignado.
+func (r *registroClaves) agregar(clave, ambito string) ClaveAPI {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	entrada := ClaveAPI{ID: r.siguienteID, Clave: clave, Ambito: ambito}
+	r.siguienteID++
+	r.claves = append(r.claves, entrada)
+	return entrada
+}
+
+// revocar marca la clave con el ID dado como revocada. Devuelve false si
+// no existe.
+func (r *registroClaves) revocar(id int) bool {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for i := range r.claves {
+		if r.claves[i].ID == id {
+			r.claves[i].Revocada = true
+			return true
+		}
+	}
+	return false
+}
+
+// validar indica si el token corresponde a una clave vigente. La
+// comparación es de tiempo constante para no filtrar prefijos.
+func (r *registroClaves) validar(token string) bool {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	valido := false
+	for i := range r.claves {
+		if r.claves[i].Revocada {
+			continue
+		}
+		if subtle.ConstantTimeCompare([]byte(r.claves[i].Clave), []byte(token)) == 1 {
+			valido = true
+		}
+	}
+	return valido
+}
+
+// MiddlewareAutenticacion exige una API key vigente en el header
+// Authorization: Bearer, dejando pasar las rutas públicas de salud y raíz.
+// La comparación del token es de tiempo constante (registroClaves.validar).
+func MiddlewareAutenticacion(claves *registroClaves, siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/salud", "/listo":
+			siguiente.ServeHTTP(w, r)
+			return
+		}
+
+		token, encontrado := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !encontrado {
+			enviarRespuestaJSON(w, http.StatusUnauthorized, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "Falta la API key Bearer",
+				CodigoError: CodigoNoAutorizado,
+			})
+			return
+		}
+		if !claves.validar(token) {
+			enviarRespuestaJSON(w, http.StatusUnauthorized, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "API key inválida",
+				CodigoError: CodigoNoAutorizado,
+			})
+			return
+		}
+
+		siguiente.ServeHTTP(w, r)
+	})
+}
+
+// enmascarar deja visibles solo los primeros caracteres de una clave.
+func enmascarar(clave string) string {
+	if len(clave) <= 4 {
+		return strings.Repeat("*", len(clave))
+	}
+	return clave[:4] + strings.Repeat("*", len(clave)-4)
+}
+
+// listar devuelve las claves con el secreto enmascarado, aptas para
+// mostrarse en el panel administrativo.
+func (r *registroClaves) listar() []map[string]interface{} {
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	salida := make([]map[string]interface{}, 0, len(r.claves))
+	for i := range r.claves {
+		salida = append(salida, map[string]interface{}{
+			"id":       r.claves[i].ID,
+			"clave":    enmascarar(r.claves[i].Clave),
+			"ambito":   r.claves[i].Ambito,
+			"revocada": r.claves[i].Revocada,
+		})
+	}
+	return salida
+}
+
+// manejarClavesAdmin lista las API keys registradas (enmascaradas).
+func (s *ServidorHTTP) manejarClavesAdmin(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		enviarRespuestaJSON(w, http.StatusMethodNotAllowed, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "Método no permitido",
+			CodigoError: CodigoMetodoNoPermitido,
+		})
+		return
+	}
+	enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+		Exitoso: true,
+		Mensaje: "API keys registradas",
+		Datos:   s.clavesAPI.listar(),
+	})
+}
+
+// manejarClaveAdminPorID revoca una API key concreta con DELETE.
+func (s *ServidorHTTP) manejarClaveAdminPorID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		enviarRespuestaJSON(w, http.StatusMethodNotAllowed, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "Método no permitido",
+			CodigoError: CodigoMetodoNoPermitido,
+		})
+		return
+	}
+
+	id, err := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/admin/keys/"))
+	if err != nil {
+		enviarRespuestaJSON(w, http.StatusBadRequest, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "El ID debe ser un número entero",
+			CodigoError: CodigoParametroInvalido,
+		})
+		return
+	}
+
+	if !s.clavesAPI.revocar(id) {
+		enviarRespuestaJSON(w, http.StatusNotFound, RespuestaJSON{
+			Exitoso: false,
+			Error:   fmt.Sprintf("No existe una clave con ID %d", id),
+		})
+		return
+	}
+
+	enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+		Exitoso: true,
+		Mensaje: fmt.Sprintf("Clave %d revocada", id),
+	})
+}
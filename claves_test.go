@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestListarYRevocarClavesAPI(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{
+		ClavesAPI: []string{"secreto-principal:escritura", "secreto-lectura:lectura"},
+	})
+
+	if !servidor.clavesAPI.validar("secreto-principal") {
+		t.Fatal("la clave recién cargada debería autenticar")
+	}
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarClavesAdmin(grabadora, httptest.NewRequest(http.MethodGet, "/admin/keys", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("listar claves debería dar 200, dio %d", grabadora.Code)
+	}
+	cuerpo := grabadora.Body.String()
+	if strings.Contains(cuerpo, "secreto-principal") {
+		t.Fatal("el listado no debe exponer la clave en claro")
+	}
+	if !strings.Contains(cuerpo, "secr") || !strings.Contains(cuerpo, "escritura") {
+		t.Fatalf("el listado debería incluir la clave enmascarada y el ámbito: %s", cuerpo)
+	}
+
+	grabadora = httptest.NewRecorder()
+	servidor.manejarClaveAdminPorID(grabadora, httptest.NewRequest(http.MethodDelete, "/admin/keys/1", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("revocar debería dar 200, dio %d", grabadora.Code)
+	}
+
+	if servidor.clavesAPI.validar("secreto-principal") {
+		t.Fatal("la clave revocada no debería autenticar")
+	}
+	if !servidor.clavesAPI.validar("secreto-lectura") {
+		t.Fatal("las demás claves deben seguir siendo válidas")
+	}
+
+	grabadora = httptest.NewRecorder()
+	servidor.manejarClaveAdminPorID(grabadora, httptest.NewRequest(http.MethodDelete, "/admin/keys/99", nil))
+	if grabadora.Code != http.StatusNotFound {
+		t.Fatalf("revocar un ID inexistente debería dar 404, dio %d", grabadora.Code)
+	}
+}
+
+func TestMiddlewareAutenticacion(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{ClaveAPI: "super-secreta"})
+	manejador := MiddlewareAutenticacion(servidor.clavesAPI, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	pedir := func(ruta, autorizacion string) int {
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodGet, ruta, nil)
+		if autorizacion != "" {
+			peticion.Header.Set("Authorization", autorizacion)
+		}
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora.Code
+	}
+
+	if codigo := pedir("/usuarios", ""); codigo != http.StatusUnauthorized {
+		t.Fatalf("sin token debería dar 401, dio %d", codigo)
+	}
+	if codigo := pedir("/usuarios", "Bearer equivocada"); codigo != http.StatusUnauthorized {
+		t.Fatalf("con token incorrecto debería dar 401, dio %d", codigo)
+	}
+	if codigo := pedir("/usuarios", "Bearer super-secreta"); codigo != http.StatusOK {
+		t.Fatalf("con token válido debería dar 200, dio %d", codigo)
+	}
+	if codigo := pedir("/salud", ""); codigo != http.StatusOK {
+		t.Fatalf("las rutas públicas no deberían exigir token, /salud dio %d", codigo)
+	}
+}
@@ -0,0 +1,418 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClienteHTTP es un cliente sencillo para consumir el API del servidor.
+type ClienteHTTP struct {
+	urlBase string
+	// cliente se comparte entre todas las llamadas para reutilizar las
+	// conexiones keep-alive del transporte; crear uno por petición obliga
+	// a renegociar TCP (y TLS) cada vez.
+	cliente *http.Client
+
+	// endpoints cachea la lista descubierta en la raíz del servidor y
+	// rutaUsuarios la ruta de usuarios resuelta a partir de ella.
+	mutexEndpoints sync.Mutex
+	endpoints      []string
+	rutaUsuarios   string
+}
+
+// Tamaño del pool de conexiones inactivas del cliente. Este cliente habla
+// con un único host, así que ambos límites coinciden.
+const maximoConexionesInactivas = 10
+
+// NuevoCliente crea un cliente apuntando a la URL base dada, con un pool
+// de conexiones reutilizables y un timeout global de 10 segundos; los
+// contextos de las variantes *ConContexto pueden acortarlo por llamada.
+func NuevoCliente(urlBase string) *ClienteHTTP {
+	return &ClienteHTTP{
+		urlBase: urlBase,
+		cliente: &http.Client{
+			Timeout: 10 * time.Second,
+			Transport: &http.Transport{
+				MaxIdleConns:        maximoConexionesInactivas,
+				MaxIdleConnsPerHost: maximoConexionesInactivas,
+			},
+		},
+	}
+}
+
+// realizarPeticion ejecuta una petición HTTP con context.Background(),
+// dejando el timeout fijo del cliente como único límite.
+func (c *ClienteHTTP) realizarPeticion(metodo, ruta string, cuerpo interface{}) ([]byte, int, error) {
+	return c.realizarPeticionConContexto(context.Background(), metodo, ruta, cuerpo)
+}
+
+// realizarPeticionConContexto ejecuta una petición HTTP respetando la
+// cancelación y los deadlines del contexto del llamador, y devuelve el
+// cuerpo de la respuesta junto con el código de estado.
+func (c *ClienteHTTP) realizarPeticionConContexto(ctx context.Context, metodo, ruta string, cuerpo interface{}) ([]byte, int, error) {
+	var lector io.Reader
+	if cuerpo != nil {
+		datos, err := json.Marshal(cuerpo)
+		if err != nil {
+			return nil, 0, fmt.Errorf("error al serializar el cuerpo: %w", err)
+		}
+		lector = bytes.NewReader(datos)
+	}
+
+	peticion, err := http.NewRequestWithContext(ctx, metodo, c.urlBase+ruta, lector)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al construir la petición: %w", err)
+	}
+	if cuerpo != nil {
+		peticion.Header.Set("Content-Type", "application/json")
+	}
+
+	respuesta, err := c.cliente.Do(peticion)
+	if err != nil {
+		return nil, 0, fmt.Errorf("error al ejecutar la petición: %w", err)
+	}
+	defer respuesta.Body.Close()
+
+	datos, err := io.ReadAll(respuesta.Body)
+	if err != nil {
+		return nil, respuesta.StatusCode, fmt.Errorf("error al leer la respuesta: %w", err)
+	}
+
+	return datos, respuesta.StatusCode, nil
+}
+
+// endpointsEsperados son las rutas que este cliente sabe consumir; si el
+// descubrimiento no las encuentra se deja una advertencia en el log.
+var endpointsEsperados = []string{"/salud", "/usuarios"}
+
+// descubrirEndpoints consulta la raíz del servidor, parsea la lista de
+// endpoints_disponibles que expone manejarInicio y la cachea para el
+// resto de la vida del cliente, de modo que un cambio de prefijo de
+// versionado se detecta sin tocar el código.
+func (c *ClienteHTTP) descubrirEndpoints() ([]string, error) {
+	return c.descubrirEndpointsConContexto(context.Background())
+}
+
+// descubrirEndpointsConContexto es la variante cancelable de
+// descubrirEndpoints.
+func (c *ClienteHTTP) descubrirEndpointsConContexto(ctx context.Context) ([]string, error) {
+	c.mutexEndpoints.Lock()
+	defer c.mutexEndpoints.Unlock()
+	return c.descubrirEndpointsBloqueado(ctx)
+}
+
+// descubrirEndpointsBloqueado hace el trabajo de descubrirEndpoints; el
+// llamador debe sostener mutexEndpoints.
+func (c *ClienteHTTP) descubrirEndpointsBloqueado(ctx context.Context) ([]string, error) {
+	if c.endpoints != nil {
+		return append([]string(nil), c.endpoints...), nil
+	}
+
+	datos, codigo, err := c.realizarPeticionConContexto(ctx, http.MethodGet, "/", nil)
+	if err != nil {
+		return nil, err
+	}
+	if codigo != http.StatusOK {
+		return nil, fmt.Errorf("descubrimiento fallido con código %d", codigo)
+	}
+
+	var respuesta struct {
+		RespuestaJSON
+		Datos struct {
+			Endpoints []string `json:"endpoints_disponibles"`
+		} `json:"datos"`
+	}
+	if err := json.Unmarshal(datos, &respuesta); err != nil {
+		return nil, fmt.Errorf("error al decodificar la respuesta: %w", err)
+	}
+	if len(respuesta.Datos.Endpoints) == 0 {
+		return nil, errors.New("el servidor no anuncia ningún endpoint en la raíz")
+	}
+
+	for _, esperado := range endpointsEsperados {
+		encontrado := false
+		for _, endpoint := range respuesta.Datos.Endpoints {
+			if strings.HasSuffix(endpoint, esperado) {
+				encontrado = true
+				break
+			}
+		}
+		if !encontrado {
+			log.Printf("Advertencia: el servidor no anuncia el endpoint esperado %s", esperado)
+		}
+	}
+
+	c.endpoints = respuesta.Datos.Endpoints
+	return append([]string(nil), c.endpoints...), nil
+}
+
+// rutaUsuariosConContexto resuelve la ruta del recurso de usuarios a
+// partir del descubrimiento, de modo que el cliente sigue al servidor si
+// cambia el prefijo de versionado. Si el descubrimiento falla se asume
+// el prefijo por defecto de esta versión; la ruta resuelta se cachea
+// para no volver a consultar la raíz en cada llamada.
+func (c *ClienteHTTP) rutaUsuariosConContexto(ctx context.Context) string {
+	c.mutexEndpoints.Lock()
+	defer c.mutexEndpoints.Unlock()
+	if c.rutaUsuarios != "" {
+		return c.rutaUsuarios
+	}
+
+	ruta := prefijoAPIPorDefecto + "/usuarios"
+	endpoints, err := c.descubrirEndpointsBloqueado(ctx)
+	if err != nil {
+		log.Printf("Advertencia: descubrimiento de endpoints fallido, se asume %s: %v", ruta, err)
+	} else {
+		for _, endpoint := range endpoints {
+			if strings.HasSuffix(endpoint, "/usuarios") {
+				ruta = endpoint
+				break
+			}
+		}
+	}
+	c.rutaUsuarios = ruta
+	return ruta
+}
+
+// verificarSalud consulta /salud y devuelve error si el servidor no está sano.
+func (c *ClienteHTTP) verificarSalud() error {
+	return c.verificarSaludConContexto(context.Background())
+}
+
+// verificarSaludConContexto es la variante cancelable de verificarSalud.
+func (c *ClienteHTTP) verificarSaludConContexto(ctx context.Context) error {
+	_, codigo, err := c.realizarPeticionConContexto(ctx, http.MethodGet, "/salud", nil)
+	if err != nil {
+		return err
+	}
+	if codigo != http.StatusOK {
+		return fmt.Errorf("el servidor respondió con código %d", codigo)
+	}
+	return nil
+}
+
+// crearUsuario da de alta un usuario y devuelve el usuario creado.
+func (c *ClienteHTTP) crearUsuario(nombre, email string) (*Usuario, error) {
+	return c.crearUsuarioConContexto(context.Background(), nombre, email)
+}
+
+// crearUsuarioConContexto es la variante cancelable de crearUsuario.
+func (c *ClienteHTTP) crearUsuarioConContexto(ctx context.Context, nombre, email string) (*Usuario, error) {
+	cuerpo := map[string]string{"nombre": nombre, "email": email}
+	datos, codigo, err := c.realizarPeticionConContexto(ctx, http.MethodPost, c.rutaUsuariosConContexto(ctx), cuerpo)
+	if err != nil {
+		return nil, err
+	}
+	if codigo != http.StatusCreated {
+		return nil, fmt.Errorf("creación fallida con código %d: %s", codigo, datos)
+	}
+
+	var respuesta struct {
+		RespuestaJSON
+		Datos Usuario `json:"datos"`
+	}
+	if err := json.Unmarshal(datos, &respuesta); err != nil {
+		return nil, fmt.Errorf("error al decodificar la respuesta: %w", err)
+	}
+	return &respuesta.Datos, nil
+}
+
+// crearUsuariosConcurrente da de alta varios usuarios en paralelo con un
+// pool de como mucho `concurrencia` goroutines. Devuelve un slice de
+// errores alineado con el de entrada: nil en las posiciones creadas y el
+// error correspondiente en las que fallaron.
+func (c *ClienteHTTP) crearUsuariosConcurrente(usuarios []map[string]string, concurrencia int) []error {
+	return c.crearUsuariosConcurrenteConContexto(context.Background(), usuarios, concurrencia)
+}
+
+// crearUsuariosConcurrenteConContexto es la variante cancelable de
+// crearUsuariosConcurrente. Al cancelarse el contexto, los usuarios aún
+// no despachados quedan marcados con ctx.Err() sin esperar al servidor.
+func (c *ClienteHTTP) crearUsuariosConcurrenteConContexto(ctx context.Context, usuarios []map[string]string, concurrencia int) []error {
+	errores := make([]error, len(usuarios))
+	if len(usuarios) == 0 {
+		return errores
+	}
+	if concurrencia < 1 {
+		concurrencia = 1
+	}
+	if concurrencia > len(usuarios) {
+		concurrencia = len(usuarios)
+	}
+
+	// Cada worker toma índices del canal; escribir cada error en su
+	// propia posición evita sincronizar el slice.
+	indices := make(chan int)
+	var grupo sync.WaitGroup
+	for i := 0; i < concurrencia; i++ {
+		grupo.Add(1)
+		go func() {
+			defer grupo.Done()
+			for indice := range indices {
+				datos := usuarios[indice]
+				_, err := c.crearUsuarioConContexto(ctx, datos["nombre"], datos["email"])
+				errores[indice] = err
+			}
+		}()
+	}
+
+despacho:
+	for indice := range usuarios {
+		select {
+		case indices <- indice:
+		case <-ctx.Done():
+			for restante := indice; restante < len(usuarios); restante++ {
+				errores[restante] = ctx.Err()
+			}
+			break despacho
+		}
+	}
+	close(indices)
+	grupo.Wait()
+	return errores
+}
+
+// obtenerUsuarios recupera la lista completa de usuarios.
+func (c *ClienteHTTP) obtenerUsuarios() ([]Usuario, error) {
+	return c.obtenerUsuariosConContexto(context.Background())
+}
+
+// obtenerUsuariosConContexto es la variante cancelable de obtenerUsuarios.
+func (c *ClienteHTTP) obtenerUsuariosConContexto(ctx context.Context) ([]Usuario, error) {
+	datos, codigo, err := c.realizarPeticionConContexto(ctx, http.MethodGet, c.rutaUsuariosConContexto(ctx), nil)
+	if err != nil {
+		return nil, err
+	}
+	if codigo != http.StatusOK {
+		return nil, fmt.Errorf("listado fallido con código %d", codigo)
+	}
+
+	var respuesta struct {
+		RespuestaJSON
+		Datos struct {
+			Usuarios []Usuario `json:"usuarios"`
+		} `json:"datos"`
+	}
+	if err := json.Unmarshal(datos, &respuesta); err != nil {
+		return nil, fmt.Errorf("error al decodificar la respuesta: %w", err)
+	}
+	return respuesta.Datos.Usuarios, nil
+}
+
+// obtenerUsuarioPorID recupera un usuario concreto por su ID.
+func (c *ClienteHTTP) obtenerUsuarioPorID(id int) (*Usuario, error) {
+	return c.obtenerUsuarioPorIDConContexto(context.Background(), id)
+}
+
+// obtenerUsuarioPorIDConContexto es la variante cancelable de
+// obtenerUsuarioPorID.
+func (c *ClienteHTTP) obtenerUsuarioPorIDConContexto(ctx context.Context, id int) (*Usuario, error) {
+	datos, codigo, err := c.realizarPeticionConContexto(ctx, http.MethodGet, fmt.Sprintf("%s/%d", c.rutaUsuariosConContexto(ctx), id), nil)
+	if err != nil {
+		return nil, err
+	}
+	if codigo != http.StatusOK {
+		return nil, fmt.Errorf("usuario %d no disponible, código %d", id, codigo)
+	}
+
+	var respuesta struct {
+		RespuestaJSON
+		Datos Usuario `json:"datos"`
+	}
+	if err := json.Unmarshal(datos, &respuesta); err != nil {
+		return nil, fmt.Errorf("error al decodificar la respuesta: %w", err)
+	}
+	return &respuesta.Datos, nil
+}
+
+// ErrUsuarioNoEncontrado señala que el servidor respondió 404 para el
+// usuario pedido; el llamador puede distinguirlo con errors.Is.
+var ErrUsuarioNoEncontrado = errors.New("el usuario no existe en el servidor")
+
+// actualizarUsuario reemplaza nombre y email del usuario con un PUT.
+func (c *ClienteHTTP) actualizarUsuario(id int, nombre, email string) error {
+	return c.actualizarUsuarioConContexto(context.Background(), id, nombre, email)
+}
+
+// actualizarUsuarioConContexto es la variante cancelable de
+// actualizarUsuario.
+func (c *ClienteHTTP) actualizarUsuarioConContexto(ctx context.Context, id int, nombre, email string) error {
+	cuerpo := map[string]string{"nombre": nombre, "email": email}
+	datos, codigo, err := c.realizarPeticionConContexto(ctx, http.MethodPut, fmt.Sprintf("%s/%d", c.rutaUsuariosConContexto(ctx), id), cuerpo)
+	if err != nil {
+		return err
+	}
+	switch codigo {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return fmt.Errorf("actualización del usuario %d: %w", id, ErrUsuarioNoEncontrado)
+	default:
+		return fmt.Errorf("actualización fallida con código %d: %s", codigo, datos)
+	}
+
+	var respuesta RespuestaJSON
+	if err := json.Unmarshal(datos, &respuesta); err != nil {
+		return fmt.Errorf("error al decodificar la respuesta: %w", err)
+	}
+	log.Printf("Usuario %d actualizado: %s", id, respuesta.Mensaje)
+	return nil
+}
+
+// eliminarUsuario borra el usuario con un DELETE.
+func (c *ClienteHTTP) eliminarUsuario(id int) error {
+	return c.eliminarUsuarioConContexto(context.Background(), id)
+}
+
+// eliminarUsuarioConContexto es la variante cancelable de eliminarUsuario.
+func (c *ClienteHTTP) eliminarUsuarioConContexto(ctx context.Context, id int) error {
+	datos, codigo, err := c.realizarPeticionConContexto(ctx, http.MethodDelete, fmt.Sprintf("%s/%d", c.rutaUsuariosConContexto(ctx), id), nil)
+	if err != nil {
+		return err
+	}
+	switch codigo {
+	case http.StatusOK, http.StatusNoContent:
+	case http.StatusNotFound:
+		return fmt.Errorf("eliminación del usuario %d: %w", id, ErrUsuarioNoEncontrado)
+	default:
+		return fmt.Errorf("eliminación fallida con código %d: %s", codigo, datos)
+	}
+
+	log.Printf("Usuario %d eliminado", id)
+	return nil
+}
+
+// ejemploCompletoDeUso demuestra el flujo típico contra un servidor local.
+func ejemploCompletoDeUso() {
+	cliente := NuevoCliente("http://localhost:8080")
+
+	if err := cliente.verificarSalud(); err != nil {
+		log.Printf("El servidor no está disponible: %v", err)
+		return
+	}
+
+	ejemplos := []map[string]string{
+		{"nombre": "María García", "email": "maria@ejemplo.com"},
+		{"nombre": "Juan Pérez", "email": "juan@ejemplo.com"},
+	}
+	for indice, err := range cliente.crearUsuariosConcurrente(ejemplos, 4) {
+		if err != nil {
+			log.Printf("Error al crear usuario %s: %v", ejemplos[indice]["email"], err)
+		}
+	}
+
+	usuarios, err := cliente.obtenerUsuarios()
+	if err != nil {
+		log.Printf("Error al listar usuarios: %v", err)
+		return
+	}
+	log.Printf("Hay %d usuarios registrados", len(usuarios))
+}
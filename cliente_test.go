@@ -0,0 +1,341 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClienteRespetaLaCancelacionDelContexto(t *testing.T) {
+	lento := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(5 * time.Second):
+		case <-r.Context().Done():
+		}
+	}))
+	defer lento.Close()
+
+	cliente := NuevoCliente(lento.URL)
+
+	ctx, cancelar := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancelar()
+
+	inicio := time.Now()
+	err := cliente.verificarSaludConContexto(ctx)
+	if err == nil {
+		t.Fatal("la petición debería abortarse al vencer el contexto")
+	}
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("se esperaba context.DeadlineExceeded, fue: %v", err)
+	}
+	if transcurrido := time.Since(inicio); transcurrido > time.Second {
+		t.Fatalf("la cancelación tardó demasiado: %v", transcurrido)
+	}
+}
+
+// responderDescubrimiento contesta la petición de descubrimiento del
+// cliente en los stubs, anunciando las rutas con el prefijo actual.
+func responderDescubrimiento(w http.ResponseWriter) {
+	enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+		Exitoso: true,
+		Datos: map[string]interface{}{
+			"endpoints_disponibles": []string{"/salud", "/api/v1/usuarios", "/api/v1/usuarios/{id}"},
+		},
+	})
+}
+
+func TestClienteActualizaYEliminaUsuarios(t *testing.T) {
+	type peticionVista struct {
+		metodo string
+		ruta   string
+		cuerpo string
+	}
+	var vista peticionVista
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			responderDescubrimiento(w)
+			return
+		}
+		datos, _ := io.ReadAll(r.Body)
+		vista = peticionVista{metodo: r.Method, ruta: r.URL.Path, cuerpo: string(datos)}
+		if strings.HasSuffix(r.URL.Path, "/999") {
+			enviarRespuestaJSON(w, http.StatusNotFound, RespuestaJSON{Exitoso: false, Error: "Usuario no encontrado"})
+			return
+		}
+		enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{Exitoso: true, Mensaje: "hecho"})
+	}))
+	defer servidor.Close()
+
+	cliente := NuevoCliente(servidor.URL)
+
+	t.Run("actualizar envía PUT con el cuerpo correcto", func(t *testing.T) {
+		if err := cliente.actualizarUsuario(7, "Ana", "ana@ejemplo.com"); err != nil {
+			t.Fatalf("actualizarUsuario devolvió error: %v", err)
+		}
+		if vista.metodo != http.MethodPut || vista.ruta != "/api/v1/usuarios/7" {
+			t.Fatalf("petición inesperada: %+v", vista)
+		}
+		if !strings.Contains(vista.cuerpo, `"nombre":"Ana"`) || !strings.Contains(vista.cuerpo, `"email":"ana@ejemplo.com"`) {
+			t.Fatalf("cuerpo inesperado: %s", vista.cuerpo)
+		}
+	})
+
+	t.Run("eliminar envía DELETE sin cuerpo", func(t *testing.T) {
+		if err := cliente.eliminarUsuario(7); err != nil {
+			t.Fatalf("eliminarUsuario devolvió error: %v", err)
+		}
+		if vista.metodo != http.MethodDelete || vista.ruta != "/api/v1/usuarios/7" || vista.cuerpo != "" {
+			t.Fatalf("petición inesperada: %+v", vista)
+		}
+	})
+
+	t.Run("el 404 es distinguible", func(t *testing.T) {
+		if err := cliente.actualizarUsuario(999, "Nadie", "nadie@ejemplo.com"); !errors.Is(err, ErrUsuarioNoEncontrado) {
+			t.Fatalf("se esperaba ErrUsuarioNoEncontrado, fue: %v", err)
+		}
+		if err := cliente.eliminarUsuario(999); !errors.Is(err, ErrUsuarioNoEncontrado) {
+			t.Fatalf("se esperaba ErrUsuarioNoEncontrado, fue: %v", err)
+		}
+	})
+}
+
+// Los benchmarks comparan el cliente compartido (conexiones keep-alive
+// reutilizadas) con el patrón anterior de crear un http.Client por
+// llamada, que renegocia TCP en cada petición.
+
+func servidorDeBenchmark(b *testing.B) *httptest.Server {
+	b.Helper()
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{Exitoso: true, Mensaje: "saludable"})
+	}))
+	b.Cleanup(servidor.Close)
+	return servidor
+}
+
+func BenchmarkClienteConConexionesReutilizadas(b *testing.B) {
+	servidor := servidorDeBenchmark(b)
+	cliente := NuevoCliente(servidor.URL)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := cliente.verificarSalud(); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkClienteNuevoPorPeticion(b *testing.B) {
+	servidor := servidorDeBenchmark(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		efimero := &http.Client{Timeout: 10 * time.Second}
+		respuesta, err := efimero.Get(servidor.URL + "/salud")
+		if err != nil {
+			b.Fatal(err)
+		}
+		if _, err := io.Copy(io.Discard, respuesta.Body); err != nil {
+			b.Fatal(err)
+		}
+		respuesta.Body.Close()
+		// Sin esto las conexiones inactivas se acumulan entre iteraciones
+		// y el benchmark mide otra cosa.
+		efimero.CloseIdleConnections()
+	}
+}
+
+func TestClienteConContextoSano(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{Exitoso: true, Mensaje: "saludable"})
+	}))
+	defer servidor.Close()
+
+	cliente := NuevoCliente(servidor.URL)
+	if err := cliente.verificarSaludConContexto(context.Background()); err != nil {
+		t.Fatalf("verificarSaludConContexto devolvió error: %v", err)
+	}
+}
+
+func TestClienteDescubreEndpointsDesdeLaRaiz(t *testing.T) {
+	var visitas int
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			enviarRespuestaJSON(w, http.StatusNotFound, RespuestaJSON{Exitoso: false})
+			return
+		}
+		visitas++
+		enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+			Exitoso: true,
+			Datos: map[string]interface{}{
+				"endpoints_disponibles": []string{"/salud", "/api/v2/usuarios", "/api/v2/usuarios/{id}"},
+			},
+		})
+	}))
+	defer servidor.Close()
+
+	cliente := NuevoCliente(servidor.URL)
+
+	endpoints, err := cliente.descubrirEndpoints()
+	if err != nil {
+		t.Fatalf("descubrirEndpoints devolvió error: %v", err)
+	}
+	if len(endpoints) != 3 || endpoints[1] != "/api/v2/usuarios" {
+		t.Fatalf("lista de endpoints inesperada: %v", endpoints)
+	}
+
+	// La segunda llamada sale de la caché, sin otra petición a la raíz.
+	if _, err := cliente.descubrirEndpoints(); err != nil {
+		t.Fatalf("la llamada cacheada devolvió error: %v", err)
+	}
+	if visitas != 1 {
+		t.Fatalf("la raíz debería consultarse una sola vez, se consultó %d", visitas)
+	}
+}
+
+func TestClienteDescubrimientoSinEndpoints(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{Exitoso: true})
+	}))
+	defer servidor.Close()
+
+	if _, err := NuevoCliente(servidor.URL).descubrirEndpoints(); err == nil {
+		t.Fatal("sin endpoints anunciados debería devolver error")
+	}
+}
+
+func TestClienteCreaUsuariosConcurrente(t *testing.T) {
+	var enVuelo, maximoVisto, creados atomic.Int32
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/" {
+			responderDescubrimiento(w)
+			return
+		}
+		actual := enVuelo.Add(1)
+		defer enVuelo.Add(-1)
+		for {
+			visto := maximoVisto.Load()
+			if actual <= visto || maximoVisto.CompareAndSwap(visto, actual) {
+				break
+			}
+		}
+
+		var cuerpo map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&cuerpo)
+		time.Sleep(10 * time.Millisecond)
+		if strings.HasPrefix(cuerpo["email"], "duplicado") {
+			enviarRespuestaJSON(w, http.StatusConflict, RespuestaJSON{Exitoso: false, Error: "ya existe"})
+			return
+		}
+		creados.Add(1)
+		enviarRespuestaJSON(w, http.StatusCreated, RespuestaJSON{
+			Exitoso: true,
+			Datos:   Usuario{ID: int(creados.Load()), Nombre: cuerpo["nombre"], Email: cuerpo["email"]},
+		})
+	}))
+	defer servidor.Close()
+
+	usuarios := make([]map[string]string, 0, 9)
+	for i := 0; i < 9; i++ {
+		email := fmt.Sprintf("usuario%d@ejemplo.com", i)
+		if i == 2 || i == 5 {
+			email = fmt.Sprintf("duplicado%d@ejemplo.com", i)
+		}
+		usuarios = append(usuarios, map[string]string{
+			"nombre": fmt.Sprintf("Usuario %d", i),
+			"email":  email,
+		})
+	}
+
+	errores := NuevoCliente(servidor.URL).crearUsuariosConcurrente(usuarios, 3)
+
+	if len(errores) != len(usuarios) {
+		t.Fatalf("se esperaban %d resultados, llegaron %d", len(usuarios), len(errores))
+	}
+	for indice, err := range errores {
+		esperabaError := indice == 2 || indice == 5
+		if esperabaError && err == nil {
+			t.Fatalf("el índice %d debería traer el error del duplicado", indice)
+		}
+		if !esperabaError && err != nil {
+			t.Fatalf("el índice %d no debería fallar: %v", indice, err)
+		}
+	}
+	if creados.Load() != 7 {
+		t.Fatalf("deberían haberse creado 7 usuarios, se crearon %d", creados.Load())
+	}
+	if maximoVisto.Load() > 3 {
+		t.Fatalf("el pool no debería superar 3 peticiones simultáneas, llegó a %d", maximoVisto.Load())
+	}
+}
+
+func TestClienteConcurrenteRespetaLaCancelacion(t *testing.T) {
+	servidor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enviarRespuestaJSON(w, http.StatusCreated, RespuestaJSON{Exitoso: true, Datos: Usuario{ID: 1}})
+	}))
+	defer servidor.Close()
+
+	ctx, cancelar := context.WithCancel(context.Background())
+	cancelar()
+
+	usuarios := []map[string]string{
+		{"nombre": "María García", "email": "maria@ejemplo.com"},
+		{"nombre": "Juan Pérez", "email": "juan@ejemplo.com"},
+	}
+	for indice, err := range NuevoCliente(servidor.URL).crearUsuariosConcurrenteConContexto(ctx, usuarios, 2) {
+		if !errors.Is(err, context.Canceled) {
+			t.Fatalf("con el contexto cancelado, el índice %d debería traer context.Canceled, fue: %v", indice, err)
+		}
+	}
+}
+
+func TestClienteContraElServidorCompleto(t *testing.T) {
+	real := httptest.NewServer(NuevoServidor(&Configuracion{}).Manejador())
+	defer real.Close()
+
+	cliente := NuevoCliente(real.URL)
+	if err := cliente.verificarSalud(); err != nil {
+		t.Fatalf("verificarSalud devolvió error: %v", err)
+	}
+
+	creado, err := cliente.crearUsuario("Ana", "ana@ejemplo.com")
+	if err != nil {
+		t.Fatalf("crearUsuario devolvió error: %v", err)
+	}
+	if creado.ID == 0 || creado.Nombre != "Ana" {
+		t.Fatalf("usuario creado inesperado: %+v", creado)
+	}
+
+	usuarios, err := cliente.obtenerUsuarios()
+	if err != nil {
+		t.Fatalf("obtenerUsuarios devolvió error: %v", err)
+	}
+	if len(usuarios) != 1 {
+		t.Fatalf("se esperaba 1 usuario, hay %d", len(usuarios))
+	}
+
+	if err := cliente.actualizarUsuario(creado.ID, "Ana María", "anamaria@ejemplo.com"); err != nil {
+		t.Fatalf("actualizarUsuario devolvió error: %v", err)
+	}
+	leido, err := cliente.obtenerUsuarioPorID(creado.ID)
+	if err != nil {
+		t.Fatalf("obtenerUsuarioPorID devolvió error: %v", err)
+	}
+	if leido.Nombre != "Ana María" {
+		t.Fatalf("la actualización no llegó al servidor: %+v", leido)
+	}
+
+	if err := cliente.eliminarUsuario(creado.ID); err != nil {
+		t.Fatalf("eliminarUsuario devolvió error: %v", err)
+	}
+	if err := cliente.eliminarUsuario(creado.ID); !errors.Is(err, ErrUsuarioNoEncontrado) {
+		t.Fatalf("el segundo borrado debería devolver ErrUsuarioNoEncontrado, fue: %v", err)
+	}
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// umbralMinimoCompresion es el tamaño de cuerpo a partir del cual vale la
+// pena comprimir; por debajo la cabecera gzip anula el ahorro.
+const umbralMinimoCompresion = 1024
+
+// escritorGzip envuelve el ResponseWriter posponiendo la decisión de
+// comprimir: el cuerpo se acumula hasta superar el umbral mínimo y, si no
+// lo alcanza, sale sin comprimir. El código de estado también se retiene
+// hasta saber qué cabeceras corresponden.
+type escritorGzip struct {
+	http.ResponseWriter
+	nivel     int
+	codigo    int
+	pendiente []byte
+	compresor *gzip.Writer
+}
+
+func (e *escritorGzip) WriteHeader(codigo int) {
+	e.codigo = codigo
+}
+
+func (e *escritorGzip) Write(datos []byte) (int, error) {
+	if e.compresor != nil {
+		return e.compresor.Write(datos)
+	}
+	e.pendiente = append(e.pendiente, datos...)
+	if len(e.pendiente) >= umbralMinimoCompresion {
+		if err := e.activarCompresion(); err != nil {
+			return 0, err
+		}
+	}
+	return len(datos), nil
+}
+
+// activarCompresion emite las cabeceras, crea el compresor y le pasa el
+// cuerpo acumulado hasta ahora.
+func (e *escritorGzip) activarCompresion() error {
+	e.ResponseWriter.Header().Set("Content-Encoding", "gzip")
+	// Un Content-Length puesto por el handler (http.ServeContent en la
+	// exportación, por ejemplo) mide el cuerpo sin comprimir; dejarlo
+	// haría que el cliente cortara la descarga comprimida a medias.
+	e.ResponseWriter.Header().Del("Content-Length")
+	e.ResponseWriter.WriteHeader(e.codigo)
+
+	compresor, err := gzip.NewWriterLevel(e.ResponseWriter, e.nivel)
+	if err != nil {
+		return err
+	}
+	e.compresor = compresor
+	_, err = compresor.Write(e.pendiente)
+	e.pendiente = nil
+	return err
+}
+
+// Flush delega en el writer interno para no cortar el streaming. Un
+// handler que pide Flush quiere que cada trozo llegue al cliente según
+// se escribe, así que no tiene sentido retener el cuerpo hasta el
+// umbral: la compresión se activa en el primer Flush y el compresor se
+// vacía antes de empujar al transporte.
+func (e *escritorGzip) Flush() {
+	if e.compresor == nil {
+		if err := e.activarCompresion(); err != nil {
+			return
+		}
+	}
+	if err := e.compresor.Flush(); err != nil {
+		return
+	}
+	if f, ok := e.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// cerrar vuelca lo que quede: cierra el compresor para no truncar el
+// cuerpo, o escribe en claro las respuestas que no llegaron al umbral.
+func (e *escritorGzip) cerrar() error {
+	if e.compresor != nil {
+		return e.compresor.Close()
+	}
+	e.ResponseWriter.WriteHeader(e.codigo)
+	_, err := e.ResponseWriter.Write(e.pendiente)
+	return err
+}
+
+// cargaAlta indica si el servidor está demasiado ocupado para gastar CPU
+// en comprimir. Se estima con las peticiones en vuelo contra el umbral
+// configurado; con umbral cero la compresión nunca se desactiva.
+func (s *ServidorHTTP) cargaAlta() bool {
+	umbral := s.configuracion.UmbralCargaCompresion
+	return umbral > 0 && s.peticionesEnVuelo.Load() >= int64(umbral)
+}
+
+// nivelCompresionEfectivo valida el nivel configurado contra el rango 1-9
+// y devuelve el nivel por defecto de gzip cuando no se especifica.
+func nivelCompresionEfectivo(nivel int) (int, error) {
+	if nivel == 0 {
+		return gzip.DefaultCompression, nil
+	}
+	if nivel < gzip.BestSpeed || nivel > gzip.BestCompression {
+		return 0, fmt.Errorf("nivel de compresión %d fuera del rango 1-9", nivel)
+	}
+	return nivel, nil
+}
+
+// MiddlewareCompresion comprime las respuestas con gzip al nivel
+// configurado cuando el cliente lo acepta y el cuerpo supera el umbral
+// mínimo. Bajo carga alta la compresión se desactiva temporalmente para
+// priorizar latencia sobre ancho de banda.
+func (s *ServidorHTTP) MiddlewareCompresion(siguiente http.Handler) http.Handler {
+	nivel, err := nivelCompresionEfectivo(s.configuracion.NivelCompresion)
+	if err != nil {
+		s.registrador.Warn("nivel de compresión inválido, se usa el por defecto", "error", err)
+		nivel = gzip.DefaultCompression
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Accept-Encoding"), "gzip") || s.cargaAlta() {
+			siguiente.ServeHTTP(w, r)
+			return
+		}
+
+		escritor := &escritorGzip{ResponseWriter: w, nivel: nivel, codigo: http.StatusOK}
+		defer func() {
+			if err := escritor.cerrar(); err != nil {
+				s.registrador.Error("error al cerrar la respuesta comprimida", "error", err)
+			}
+		}()
+
+		siguiente.ServeHTTP(escritor, r)
+	})
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNivelDeCompresionConfigurable(t *testing.T) {
+	// Contenido con algo de variedad para que el nivel influya en el ratio.
+	var contenido strings.Builder
+	for i := 0; i < 2000; i++ {
+		fmt.Fprintf(&contenido, "usuario-%d email-%d@ejemplo.com ", i%97, i%53)
+	}
+	fondo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, contenido.String())
+	})
+
+	comprimir := func(nivel int) int {
+		servidor := NuevoServidor(&Configuracion{NivelCompresion: nivel})
+		peticion := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+		peticion.Header.Set("Accept-Encoding", "gzip")
+		grabadora := httptest.NewRecorder()
+		servidor.MiddlewareCompresion(fondo).ServeHTTP(grabadora, peticion)
+		return grabadora.Body.Len()
+	}
+
+	if alto, bajo := comprimir(9), comprimir(1); alto >= bajo {
+		t.Fatalf("nivel 9 (%d bytes) debería comprimir más que nivel 1 (%d bytes)", alto, bajo)
+	}
+}
+
+func TestNivelCompresionFueraDeRango(t *testing.T) {
+	if _, err := nivelCompresionEfectivo(12); err == nil {
+		t.Fatal("un nivel fuera de 1-9 debería rechazarse")
+	}
+	if nivel, err := nivelCompresionEfectivo(0); err != nil || nivel == 0 {
+		t.Fatalf("el nivel 0 debería caer al por defecto, dio (%d, %v)", nivel, err)
+	}
+}
+
+func TestMiddlewareCompresionAdaptativa(t *testing.T) {
+	contenido := strings.Repeat("usuarios y más usuarios ", 100)
+	fondo := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, contenido)
+	})
+
+	pedir := func(servidor *ServidorHTTP, conGzip bool) *httptest.ResponseRecorder {
+		peticion := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+		if conGzip {
+			peticion.Header.Set("Accept-Encoding", "gzip")
+		}
+		grabadora := httptest.NewRecorder()
+		servidor.MiddlewareCompresion(fondo).ServeHTTP(grabadora, peticion)
+		return grabadora
+	}
+
+	t.Run("comprime con carga normal", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{UmbralCargaCompresion: 10})
+		grabadora := pedir(servidor, true)
+		if grabadora.Header().Get("Content-Encoding") != "gzip" {
+			t.Fatal("se esperaba respuesta comprimida")
+		}
+		lector, err := gzip.NewReader(grabadora.Body)
+		if err != nil {
+			t.Fatalf("el cuerpo no es gzip válido: %v", err)
+		}
+		descomprimido, _ := io.ReadAll(lector)
+		if string(descomprimido) != contenido {
+			t.Fatal("el contenido descomprimido no coincide")
+		}
+	})
+
+	t.Run("no comprime bajo carga alta", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{UmbralCargaCompresion: 2})
+		servidor.peticionesEnVuelo.Store(5)
+		grabadora := pedir(servidor, true)
+		if grabadora.Header().Get("Content-Encoding") == "gzip" {
+			t.Fatal("bajo carga alta no debería comprimir")
+		}
+		if grabadora.Body.String() != contenido {
+			t.Fatal("el cuerpo sin comprimir no coincide")
+		}
+	})
+
+	t.Run("no comprime respuestas pequeñas", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		pequeno := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{Exitoso: true, Mensaje: "saludable"})
+		})
+		peticion := httptest.NewRequest(http.MethodGet, "/salud", nil)
+		peticion.Header.Set("Accept-Encoding", "gzip")
+		grabadora := httptest.NewRecorder()
+		servidor.MiddlewareCompresion(pequeno).ServeHTTP(grabadora, peticion)
+
+		if grabadora.Header().Get("Content-Encoding") == "gzip" {
+			t.Fatal("un cuerpo menor del umbral no debería comprimirse")
+		}
+		if !strings.Contains(grabadora.Body.String(), "saludable") {
+			t.Fatalf("el cuerpo pequeño debería salir en claro: %q", grabadora.Body.String())
+		}
+	})
+
+	t.Run("no comprime sin Accept-Encoding", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		grabadora := pedir(servidor, false)
+		if grabadora.Header().Get("Content-Encoding") == "gzip" {
+			t.Fatal("sin Accept-Encoding no debería comprimir")
+		}
+	})
+}
+
+func TestExportacionComprimidaNoConservaContentLength(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	// Suficientes usuarios para que el CSV supere el umbral de compresión.
+	for i := 0; i < 40; i++ {
+		crearUsuarioDePrueba(t, servidor, fmt.Sprintf("Usuario %d", i), fmt.Sprintf("usuario%d@ejemplo.com", i))
+	}
+	manejador := servidor.Manejador()
+
+	exportar := func(aceptar string) *httptest.ResponseRecorder {
+		peticion := httptest.NewRequest(http.MethodGet, "/api/v1/usuarios/exportar", nil)
+		if aceptar != "" {
+			peticion.Header.Set("Accept-Encoding", aceptar)
+		}
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora
+	}
+
+	plano := exportar("")
+	comprimida := exportar("gzip")
+	if codificacion := comprimida.Header().Get("Content-Encoding"); codificacion != "gzip" {
+		t.Fatalf("la exportación debería salir comprimida, Content-Encoding %q", codificacion)
+	}
+
+	// http.ServeContent fija el Content-Length del cuerpo sin comprimir;
+	// si sobrevive a la compresión, el cliente corta la descarga a medias.
+	if longitud := comprimida.Header().Get("Content-Length"); longitud != "" && longitud != fmt.Sprint(comprimida.Body.Len()) {
+		t.Fatalf("Content-Length %s no corresponde al cuerpo comprimido de %d bytes", longitud, comprimida.Body.Len())
+	}
+
+	lector, err := gzip.NewReader(comprimida.Body)
+	if err != nil {
+		t.Fatalf("el cuerpo no es gzip válido: %v", err)
+	}
+	descomprimido, err := io.ReadAll(lector)
+	if err != nil {
+		t.Fatalf("no se pudo descomprimir la exportación: %v", err)
+	}
+	if string(descomprimido) != plano.Body.String() {
+		t.Fatalf("la exportación comprimida no coincide con la plana: %d vs %d bytes", len(descomprimido), plano.Body.Len())
+	}
+}
+
+func TestEscritorGzipDelegaFlushParaStreaming(t *testing.T) {
+	grabadora := httptest.NewRecorder()
+	var w http.ResponseWriter = &escritorGzip{ResponseWriter: grabadora, nivel: gzip.DefaultCompression, codigo: http.StatusOK}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		t.Fatal("escritorGzip debería implementar http.Flusher")
+	}
+
+	if _, err := w.Write([]byte("linea 1\n")); err != nil {
+		t.Fatal(err)
+	}
+	flusher.Flush()
+
+	if !grabadora.Flushed {
+		t.Fatal("Flush no llegó al writer interno")
+	}
+	if grabadora.Header().Get("Content-Encoding") != "gzip" {
+		t.Fatal("una respuesta en streaming debería comprimirse desde el primer Flush")
+	}
+
+	// La línea debe poder leerse ya, sin esperar al cierre del compresor.
+	lector, err := gzip.NewReader(bytes.NewReader(grabadora.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("el cuerpo no es gzip válido: %v", err)
+	}
+	linea := make([]byte, len("linea 1\n"))
+	if _, err := io.ReadFull(lector, linea); err != nil {
+		t.Fatalf("la línea debería estar disponible tras el Flush: %v", err)
+	}
+	if string(linea) != "linea 1\n" {
+		t.Fatalf("línea inesperada: %q", linea)
+	}
+}
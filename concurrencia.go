@@ -0,0 +1,80 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+// esperaConcurrenciaPorDefecto es cuánto aguarda una petición por un slot
+// libre antes de rendirse con 503, si no se configuró otro plazo.
+const esperaConcurrenciaPorDefecto = 500 * time.Millisecond
+
+// limitadorConcurrencia acota cuántas peticiones se atienden a la vez
+// con un semáforo de canal con buffer: cada petición ocupa un slot
+// mientras dura y lo devuelve al terminar.
+type limitadorConcurrencia struct {
+	slots  chan struct{}
+	espera time.Duration
+}
+
+func nuevoLimitadorConcurrencia(maximo int, espera time.Duration) *limitadorConcurrencia {
+	if espera <= 0 {
+		espera = esperaConcurrenciaPorDefecto
+	}
+	return &limitadorConcurrencia{
+		slots:  make(chan struct{}, maximo),
+		espera: espera,
+	}
+}
+
+// adquirir toma un slot, esperando hasta el plazo configurado si están
+// todos ocupados. Devuelve false si venció el plazo o el cliente se fue.
+func (l *limitadorConcurrencia) adquirir(ctx context.Context) bool {
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	default:
+	}
+
+	temporizador := time.NewTimer(l.espera)
+	defer temporizador.Stop()
+	select {
+	case l.slots <- struct{}{}:
+		return true
+	case <-temporizador.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (l *limitadorConcurrencia) liberar() {
+	<-l.slots
+}
+
+// ocupados devuelve cuántos slots están tomados ahora mismo.
+func (l *limitadorConcurrencia) ocupados() int {
+	return len(l.slots)
+}
+
+// MiddlewareConcurrencia aplica el límite global de peticiones
+// simultáneas: las que no consiguen slot dentro del plazo reciben 503
+// con Retry-After, señal de sobrecarga momentánea y no de error del
+// cliente. A diferencia del límite de tasa, aquí no importa quién pide
+// sino cuántos están dentro.
+func MiddlewareConcurrencia(limitador *limitadorConcurrencia, siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !limitador.adquirir(r.Context()) {
+			w.Header().Set("Retry-After", "1")
+			enviarRespuestaJSON(w, http.StatusServiceUnavailable, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "El servidor está atendiendo el máximo de peticiones simultáneas",
+				CodigoError: CodigoServicioNoDisponible,
+			})
+			return
+		}
+		defer limitador.liberar()
+		siguiente.ServeHTTP(w, r)
+	})
+}
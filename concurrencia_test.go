@@ -0,0 +1,106 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareConcurrenciaRechazaConSlotsAgotados(t *testing.T) {
+	bloqueo := make(chan struct{})
+	// Con buffer: la petición final de verificación también pasa por el
+	// handler y no debe quedarse bloqueada anunciándose.
+	dentro := make(chan struct{}, 2)
+	limitador := nuevoLimitadorConcurrencia(1, 20*time.Millisecond)
+	manejador := MiddlewareConcurrencia(limitador, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dentro <- struct{}{}
+		<-bloqueo
+	}))
+
+	// Una petición ocupa el único slot y se queda dentro.
+	go func() {
+		manejador.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+	}()
+	<-dentro
+
+	grabadora := httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+	if grabadora.Code != http.StatusServiceUnavailable {
+		t.Fatalf("sin slots libres se esperaba 503, obtenido %d", grabadora.Code)
+	}
+	if grabadora.Header().Get("Retry-After") == "" {
+		t.Fatal("el 503 debería traer Retry-After")
+	}
+
+	// Al liberarse el slot vuelve a haber sitio.
+	close(bloqueo)
+	esperarCondicion(t, time.Second, func() bool { return limitador.ocupados() == 0 })
+	grabadora = httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/salud", nil))
+	if grabadora.Code == http.StatusServiceUnavailable {
+		t.Fatal("con el slot libre la petición debería atenderse")
+	}
+}
+
+func TestMiddlewareConcurrenciaSerializaDentroDelPlazo(t *testing.T) {
+	var enCurso, maximoVisto atomic.Int32
+	limitador := nuevoLimitadorConcurrencia(2, 2*time.Second)
+	manejador := MiddlewareConcurrencia(limitador, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		actual := enCurso.Add(1)
+		for {
+			visto := maximoVisto.Load()
+			if actual <= visto || maximoVisto.CompareAndSwap(visto, actual) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		enCurso.Add(-1)
+	}))
+
+	var espera sync.WaitGroup
+	var rechazadas atomic.Int32
+	for i := 0; i < 8; i++ {
+		espera.Add(1)
+		go func() {
+			defer espera.Done()
+			grabadora := httptest.NewRecorder()
+			manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+			if grabadora.Code == http.StatusServiceUnavailable {
+				rechazadas.Add(1)
+			}
+		}()
+	}
+	espera.Wait()
+
+	if rechazadas.Load() != 0 {
+		t.Fatalf("con plazo holgado todas deberían serializarse, %d recibieron 503", rechazadas.Load())
+	}
+	if maximoVisto.Load() > 2 {
+		t.Fatalf("nunca debería haber más de 2 peticiones dentro, hubo %d", maximoVisto.Load())
+	}
+}
+
+func TestEstadoExponePeticionesEnVuelo(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	servidor.peticionesEnVuelo.Store(3)
+	defer servidor.peticionesEnVuelo.Store(0)
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarEstado(grabadora, httptest.NewRequest(http.MethodGet, "/estado", nil))
+
+	var respuesta struct {
+		Datos struct {
+			EnVuelo int `json:"peticiones_en_vuelo"`
+		} `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatal(err)
+	}
+	if respuesta.Datos.EnVuelo != 3 {
+		t.Fatalf("se esperaban 3 peticiones en vuelo, se reportan %d", respuesta.Datos.EnVuelo)
+	}
+}
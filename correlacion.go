@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"net/http"
+)
+
+// claveContexto evita colisiones con claves de contexto de otros paquetes.
+type claveContexto string
+
+const claveIDCorrelacion claveContexto = "id_correlacion"
+
+// cabeceraIDCorrelacion es el header estándar de entrada y salida del ID.
+const cabeceraIDCorrelacion = "X-Request-ID"
+
+// generarIDCorrelacion produce un UUID v4 aleatorio.
+func generarIDCorrelacion() string {
+	var bytes [16]byte
+	if _, err := rand.Read(bytes[:]); err != nil {
+		// rand.Read sobre el lector del sistema no falla en la práctica;
+		// si lo hiciera, un ID vacío es preferible a tumbar la petición.
+		return ""
+	}
+	bytes[6] = (bytes[6] & 0x0f) | 0x40 // versión 4
+	bytes[8] = (bytes[8] & 0x3f) | 0x80 // variante RFC 4122
+	return fmt.Sprintf("%x-%x-%x-%x-%x", bytes[0:4], bytes[4:6], bytes[6:8], bytes[8:10], bytes[10:16])
+}
+
+// IDDeContexto devuelve el ID de correlación de la petición, o cadena
+// vacía si el middleware no está en la cadena.
+func IDDeContexto(ctx context.Context) string {
+	if id, ok := ctx.Value(claveIDCorrelacion).(string); ok {
+		return id
+	}
+	return ""
+}
+
+// MiddlewareIDCorrelacion asigna a cada petición un ID de correlación:
+// reutiliza el X-Request-ID entrante si viene y genera un UUID si no. El
+// ID viaja en el contexto de la petición y se devuelve en el header de
+// respuesta para que el cliente pueda citarlo al reportar problemas.
+func MiddlewareIDCorrelacion(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(cabeceraIDCorrelacion)
+		if id == "" {
+			id = generarIDCorrelacion()
+		}
+
+		w.Header().Set(cabeceraIDCorrelacion, id)
+		ctx := context.WithValue(r.Context(), claveIDCorrelacion, id)
+		siguiente.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
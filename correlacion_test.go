@@ -0,0 +1,47 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+func TestMiddlewareIDCorrelacionReutilizaElEntrante(t *testing.T) {
+	var idEnContexto string
+	manejador := MiddlewareIDCorrelacion(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		idEnContexto = IDDeContexto(r.Context())
+	}))
+
+	grabadora := httptest.NewRecorder()
+	peticion := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+	peticion.Header.Set(cabeceraIDCorrelacion, "id-del-proxy")
+	manejador.ServeHTTP(grabadora, peticion)
+
+	if devuelto := grabadora.Header().Get(cabeceraIDCorrelacion); devuelto != "id-del-proxy" {
+		t.Fatalf("la respuesta debería devolver el ID entrante, devolvió %q", devuelto)
+	}
+	if idEnContexto != "id-del-proxy" {
+		t.Fatalf("el contexto debería llevar el ID entrante, lleva %q", idEnContexto)
+	}
+}
+
+func TestMiddlewareIDCorrelacionGeneraUUID(t *testing.T) {
+	manejador := MiddlewareIDCorrelacion(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	grabadora := httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+
+	id := grabadora.Header().Get(cabeceraIDCorrelacion)
+	formatoUUID := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !formatoUUID.MatchString(id) {
+		t.Fatalf("sin ID entrante debería generarse un UUID v4, se generó %q", id)
+	}
+}
+
+func TestIDDeContextoSinMiddleware(t *testing.T) {
+	peticion := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+	if id := IDDeContexto(peticion.Context()); id != "" {
+		t.Fatalf("sin middleware el ID debería ser vacío, es %q", id)
+	}
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// resultadoImportacion describe qué pasó con una línea del CSV importado;
+// Linea es el número de línea real del archivo, contando la cabecera.
+type resultadoImportacion struct {
+	Linea int    `json:"linea"`
+	ID    int    `json:"id,omitempty"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error,omitempty"`
+}
+
+// columnasCSV localiza las columnas nombre y email en la cabecera, sin
+// importar el orden ni las columnas extra (id, fechas) que trae una
+// exportación nuestra.
+func columnasCSV(cabecera []string) (nombre, email int, err error) {
+	nombre, email = -1, -1
+	for i, columna := range cabecera {
+		switch strings.ToLower(strings.TrimSpace(columna)) {
+		case "nombre":
+			nombre = i
+		case "email":
+			email = i
+		}
+	}
+	if nombre == -1 || email == -1 {
+		return 0, 0, errors.New("el CSV debe tener columnas nombre y email")
+	}
+	return nombre, email, nil
+}
+
+// manejarImportarCSV crea usuarios desde un cuerpo text/csv con columnas
+// nombre,email, reusando las validaciones de la creación individual. La
+// respuesta resume las filas creadas y los errores línea a línea, con la
+// misma convención de códigos que el lote JSON.
+func (s *ServidorHTTP) manejarImportarCSV(w http.ResponseWriter, r *http.Request) {
+	if !s.exigirAlmacenListo(w) {
+		return
+	}
+	if r.Method != http.MethodPost {
+		enviarRespuestaJSON(w, http.StatusMethodNotAllowed, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "Método no permitido",
+			CodigoError: CodigoMetodoNoPermitido,
+		})
+		return
+	}
+	if tipoDeContenido(r) != "text/csv" {
+		enviarRespuestaJSON(w, http.StatusUnsupportedMediaType, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "La importación requiere Content-Type text/csv",
+			CodigoError: CodigoFormatoNoSoportado,
+		})
+		return
+	}
+
+	lector := csv.NewReader(r.Body)
+	lector.TrimLeadingSpace = true
+
+	cabecera, err := lector.Read()
+	if err != nil {
+		enviarRespuestaJSON(w, http.StatusBadRequest, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "El CSV está vacío o es ilegible",
+			CodigoError: CodigoCuerpoInvalido,
+		})
+		return
+	}
+	columnaNombre, columnaEmail, err := columnasCSV(cabecera)
+	if err != nil {
+		enviarRespuestaJSON(w, http.StatusBadRequest, RespuestaJSON{
+			Exitoso:     false,
+			Error:       err.Error(),
+			CodigoError: CodigoCuerpoInvalido,
+		})
+		return
+	}
+
+	filas, err := lector.ReadAll()
+	if err != nil {
+		enviarRespuestaJSON(w, http.StatusBadRequest, RespuestaJSON{
+			Exitoso:     false,
+			Error:       fmt.Sprintf("CSV malformado: %v", err),
+			CodigoError: CodigoCuerpoInvalido,
+		})
+		return
+	}
+	if len(filas) == 0 {
+		enviarRespuestaJSON(w, http.StatusBadRequest, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "El CSV no trae ninguna fila de datos",
+			CodigoError: CodigoCuerpoInvalido,
+		})
+		return
+	}
+
+	resultados := make([]resultadoImportacion, 0, len(filas))
+	creados := 0
+
+	// Igual que el lote JSON: todo bajo el mismo lock para que los
+	// duplicados se detecten contra un estado estable.
+	s.mutex.Lock()
+	if s.limiteDeUsuariosAlcanzado(w) {
+		s.mutex.Unlock()
+		return
+	}
+	existentes, err := s.almacen.ObtenerTodos()
+	if err != nil {
+		s.mutex.Unlock()
+		s.errorDeAlmacen(w, err)
+		return
+	}
+	vistos := map[string]bool{}
+	for indice, fila := range filas {
+		linea := indice + 2 // la cabecera es la línea 1
+		if columnaNombre >= len(fila) || columnaEmail >= len(fila) {
+			resultados = append(resultados, resultadoImportacion{Linea: linea, Error: "la fila tiene menos columnas que la cabecera"})
+			continue
+		}
+		email := strings.TrimSpace(fila[columnaEmail])
+		emailNormalizado := strings.ToLower(email)
+
+		razon := ""
+		nombre, errNombre := s.validarNombre(fila[columnaNombre])
+		switch {
+		case s.configuracion.MaximoUsuarios > 0 && len(existentes) >= s.configuracion.MaximoUsuarios:
+			razon = "se alcanzó el máximo de usuarios configurado"
+		case errNombre != nil:
+			razon = errNombre.Error()
+		case !validarEmail(email):
+			razon = "el email no tiene un formato válido"
+		case !s.dominioAdmitido(email):
+			razon = "el dominio del email no está entre los permitidos"
+		case s.esEmailDesechable(email):
+			razon = "no se aceptan emails de dominios desechables"
+		case s.reglas.EmailUnico && vistos[emailNormalizado]:
+			razon = "email duplicado dentro del archivo"
+		}
+		if razon == "" && s.reglas.EmailUnico {
+			for i := range existentes {
+				if strings.EqualFold(existentes[i].Email, email) {
+					razon = "ya existe un usuario con ese email"
+					break
+				}
+			}
+		}
+		if razon != "" {
+			resultados = append(resultados, resultadoImportacion{Linea: linea, Email: email, Error: razon})
+			continue
+		}
+
+		vistos[emailNormalizado] = true
+		guardado, err := s.almacen.Crear(Usuario{
+			Nombre: nombre,
+			Email:  email,
+			Creado: s.marcaDeTiempo(),
+		})
+		if err != nil {
+			if errors.Is(err, ErrEmailDuplicado) {
+				resultados = append(resultados, resultadoImportacion{Linea: linea, Email: email, Error: "ya existe un usuario con ese email"})
+			} else {
+				s.registrador.Error("error del almacén al importar CSV", "error", err, "linea", linea)
+				resultados = append(resultados, resultadoImportacion{Linea: linea, Email: email, Error: "error interno al guardar el usuario"})
+			}
+			continue
+		}
+		existentes = append(existentes, guardado)
+		creados++
+		resultados = append(resultados, resultadoImportacion{Linea: linea, ID: guardado.ID, Email: guardado.Email})
+	}
+	s.mutex.Unlock()
+
+	if creados > 0 {
+		s.persistirUsuarios()
+	}
+
+	codigo := http.StatusCreated
+	if creados < len(filas) {
+		codigo = http.StatusMultiStatus
+	}
+	enviarRespuestaJSON(w, codigo, RespuestaJSON{
+		Exitoso: creados > 0,
+		Mensaje: fmt.Sprintf("%d de %d filas importadas", creados, len(filas)),
+		Datos: map[string]interface{}{
+			"creados":    creados,
+			"fallidos":   len(filas) - creados,
+			"resultados": resultados,
+		},
+	})
+}
+
+// manejarExportarCSV entrega la lista completa como descarga CSV. Las
+// columnas nombre y email permiten reimportar el archivo tal cual. El
+// cuerpo se materializa en memoria para servirlo con http.ServeContent,
+// que atiende el header Range (206 con Content-Range) y anuncia
+// Accept-Ranges: bytes; así una descarga grande cortada se reanuda desde
+// el byte donde quedó.
+func (s *ServidorHTTP) manejarExportarCSV(w http.ResponseWriter, r *http.Request) {
+	if !s.exigirAlmacenListo(w) {
+		return
+	}
+	if r.Method != http.MethodGet {
+		enviarRespuestaJSON(w, http.StatusMethodNotAllowed, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "Método no permitido",
+			CodigoError: CodigoMetodoNoPermitido,
+		})
+		return
+	}
+
+	usuarios, err := s.almacen.ObtenerTodos()
+	if err != nil {
+		s.errorDeAlmacen(w, err)
+		return
+	}
+
+	var cuerpo bytes.Buffer
+	escritor := csv.NewWriter(&cuerpo)
+	_ = escritor.Write([]string{"id", "nombre", "email", "fecha_creado", "fecha_actualizado"})
+	for _, usuario := range usuarios {
+		_ = escritor.Write([]string{
+			strconv.Itoa(usuario.ID),
+			usuario.Nombre,
+			usuario.Email,
+			usuario.Creado,
+			usuario.Actualizado,
+		})
+	}
+	escritor.Flush()
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="usuarios.csv"`)
+	// Sin fecha de modificación: la lista cambia con cada escritura y no
+	// queremos que un If-Modified-Since esconda datos nuevos.
+	http.ServeContent(w, r, "usuarios.csv", time.Time{}, bytes.NewReader(cuerpo.Bytes()))
+}
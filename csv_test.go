@@ -0,0 +1,186 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestExportarCSVConCabecerasDeDescarga(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "Benito", "benito@ejemplo.com")
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarExportarCSV(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios/exportar", nil))
+
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+	}
+	if tipo := grabadora.Header().Get("Content-Type"); !strings.HasPrefix(tipo, "text/csv") {
+		t.Fatalf("Content-Type esperado text/csv, obtenido %q", tipo)
+	}
+	if disposicion := grabadora.Header().Get("Content-Disposition"); !strings.Contains(disposicion, "attachment") {
+		t.Fatalf("la exportación debería ser una descarga: %q", disposicion)
+	}
+
+	filas, err := csv.NewReader(grabadora.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("la exportación no es CSV válido: %v", err)
+	}
+	if len(filas) != 3 {
+		t.Fatalf("se esperaban cabecera + 2 filas, hay %d", len(filas))
+	}
+	if filas[0][1] != "nombre" || filas[0][2] != "email" {
+		t.Fatalf("cabecera inesperada: %v", filas[0])
+	}
+	if filas[1][1] != "Ana" || filas[2][2] != "benito@ejemplo.com" {
+		t.Fatalf("filas inesperadas: %v", filas[1:])
+	}
+}
+
+func TestExportarCSVConRangoDeBytes(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "Benito", "benito@ejemplo.com")
+
+	exportar := func(rango string) *httptest.ResponseRecorder {
+		peticion := httptest.NewRequest(http.MethodGet, "/usuarios/exportar", nil)
+		if rango != "" {
+			peticion.Header.Set("Range", rango)
+		}
+		grabadora := httptest.NewRecorder()
+		servidor.manejarExportarCSV(grabadora, peticion)
+		return grabadora
+	}
+
+	completa := exportar("")
+	if completa.Code != http.StatusOK {
+		t.Fatalf("sin Range se esperaba 200, obtenido %d", completa.Code)
+	}
+	if acepta := completa.Header().Get("Accept-Ranges"); acepta != "bytes" {
+		t.Fatalf("la exportación debería anunciar Accept-Ranges: bytes, fue %q", acepta)
+	}
+	cuerpo := completa.Body.String()
+
+	parcial := exportar("bytes=10-29")
+	if parcial.Code != http.StatusPartialContent {
+		t.Fatalf("con Range se esperaba 206, obtenido %d: %s", parcial.Code, parcial.Body.String())
+	}
+	if segmento := parcial.Body.String(); segmento != cuerpo[10:30] {
+		t.Fatalf("segmento esperado %q, obtenido %q", cuerpo[10:30], segmento)
+	}
+	esperado := fmt.Sprintf("bytes 10-29/%d", len(cuerpo))
+	if contentRange := parcial.Header().Get("Content-Range"); contentRange != esperado {
+		t.Fatalf("Content-Range esperado %q, obtenido %q", esperado, contentRange)
+	}
+
+	// Un Range abierto por la derecha reanuda la descarga hasta el final.
+	cola := exportar(fmt.Sprintf("bytes=%d-", len(cuerpo)-15))
+	if cola.Code != http.StatusPartialContent || cola.Body.String() != cuerpo[len(cuerpo)-15:] {
+		t.Fatalf("la reanudación no devolvió la cola esperada: %d %q", cola.Code, cola.Body.String())
+	}
+
+	imposible := exportar(fmt.Sprintf("bytes=%d-", len(cuerpo)+100))
+	if imposible.Code != http.StatusRequestedRangeNotSatisfiable {
+		t.Fatalf("un rango fuera del cuerpo debería dar 416, dio %d", imposible.Code)
+	}
+}
+
+func TestImportarCSVRoundTrip(t *testing.T) {
+	origen := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, origen, "Ana", "ana@ejemplo.com")
+	crearUsuarioDePrueba(t, origen, "Benito", "benito@ejemplo.com")
+
+	grabadora := httptest.NewRecorder()
+	origen.manejarExportarCSV(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios/exportar", nil))
+	exportado := grabadora.Body.String()
+
+	// El archivo exportado se importa tal cual en un servidor vacío.
+	destino := NuevoServidor(&Configuracion{})
+	peticion := httptest.NewRequest(http.MethodPost, "/usuarios/importar", strings.NewReader(exportado))
+	peticion.Header.Set("Content-Type", "text/csv")
+	grabadora = httptest.NewRecorder()
+	destino.manejarImportarCSV(grabadora, peticion)
+
+	if grabadora.Code != http.StatusCreated {
+		t.Fatalf("código esperado 201, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+	}
+	if total := contarEnAlmacen(t, destino.almacen); total != 2 {
+		t.Fatalf("el round-trip debería dejar 2 usuarios, hay %d", total)
+	}
+	usuarios, err := destino.almacen.ObtenerTodos()
+	if err != nil {
+		t.Fatalf("error al leer el almacén: %v", err)
+	}
+	if usuarios[0].Nombre != "Ana" || usuarios[1].Email != "benito@ejemplo.com" {
+		t.Fatalf("los usuarios importados no coinciden: %+v", usuarios)
+	}
+}
+
+func TestImportarCSVReportaErroresPorLinea(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	cuerpo := "nombre,email\n" +
+		"Benito,benito@ejemplo.com\n" +
+		"Carla,no-es-un-email\n" +
+		"Duplicada,ana@ejemplo.com\n"
+	peticion := httptest.NewRequest(http.MethodPost, "/usuarios/importar", strings.NewReader(cuerpo))
+	peticion.Header.Set("Content-Type", "text/csv")
+	grabadora := httptest.NewRecorder()
+	servidor.manejarImportarCSV(grabadora, peticion)
+
+	if grabadora.Code != http.StatusMultiStatus {
+		t.Fatalf("con errores parciales se esperaba 207, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+	}
+	respuesta := grabadora.Body.String()
+	if !strings.Contains(respuesta, "1 de 3 filas importadas") {
+		t.Fatalf("falta el resumen de filas: %s", respuesta)
+	}
+	// Los errores citan la línea real del archivo (la cabecera es la 1).
+	if !strings.Contains(respuesta, `"linea":3`) || !strings.Contains(respuesta, "formato válido") {
+		t.Fatalf("falta el error de email inválido en la línea 3: %s", respuesta)
+	}
+	if !strings.Contains(respuesta, `"linea":4`) || !strings.Contains(respuesta, "ya existe un usuario") {
+		t.Fatalf("falta el error de duplicado en la línea 4: %s", respuesta)
+	}
+	if total := contarEnAlmacen(t, servidor.almacen); total != 2 {
+		t.Fatalf("solo la fila válida debería crearse: %d usuarios", total)
+	}
+}
+
+func TestImportarCSVExigeContentTypeCSV(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	peticion := httptest.NewRequest(http.MethodPost, "/usuarios/importar", strings.NewReader("nombre,email\nAna,ana@ejemplo.com\n"))
+	peticion.Header.Set("Content-Type", "application/json")
+	grabadora := httptest.NewRecorder()
+	servidor.manejarImportarCSV(grabadora, peticion)
+
+	if grabadora.Code != http.StatusUnsupportedMediaType {
+		t.Fatalf("sin text/csv se esperaba 415, obtenido %d", grabadora.Code)
+	}
+	if !strings.Contains(grabadora.Body.String(), CodigoFormatoNoSoportado) {
+		t.Fatalf("falta el código de error: %s", grabadora.Body.String())
+	}
+}
+
+func TestImportarCSVSinColumnasRequeridas(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	peticion := httptest.NewRequest(http.MethodPost, "/usuarios/importar", strings.NewReader("apodo,telefono\nAna,555\n"))
+	peticion.Header.Set("Content-Type", "text/csv")
+	grabadora := httptest.NewRecorder()
+	servidor.manejarImportarCSV(grabadora, peticion)
+
+	if grabadora.Code != http.StatusBadRequest {
+		t.Fatalf("sin columnas nombre y email se esperaba 400, obtenido %d", grabadora.Code)
+	}
+	if !strings.Contains(grabadora.Body.String(), "columnas nombre y email") {
+		t.Fatalf("el error debería nombrar las columnas requeridas: %s", grabadora.Body.String())
+	}
+}
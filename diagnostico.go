@@ -0,0 +1,78 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"strings"
+)
+
+// ipRemotaDetectada replica la detección de identidad del límite de tasa:
+// la IP remota sin puerto o, si se confía en el proxy, la primera IP de
+// X-Forwarded-For.
+func (s *ServidorHTTP) ipRemotaDetectada(r *http.Request) string {
+	if s.configuracion.ConfiarEnXForwardedFor {
+		if reenviada := r.Header.Get("X-Forwarded-For"); reenviada != "" {
+			primera, _, _ := strings.Cut(reenviada, ",")
+			if ip := strings.TrimSpace(primera); ip != "" {
+				return ip
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// esquemaDetectado devuelve el esquema con el que el servidor ve la
+// petición: el de la conexión o, detrás de un proxy de confianza, el que
+// anuncie X-Forwarded-Proto.
+func (s *ServidorHTTP) esquemaDetectado(r *http.Request) string {
+	if s.configuracion.ConfiarEnXForwardedFor {
+		if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+			return proto
+		}
+	}
+	if r.TLS != nil {
+		return "https"
+	}
+	return "http"
+}
+
+// manejarDiagnostico devuelve un eco de lo que el servidor ve de la
+// petición: método, cabeceras, IP detectada, esquema y Host. Sirve para
+// comprobar que un balanceador reenvía X-Forwarded-For y
+// X-Forwarded-Proto como se espera. Authorization se redacta para que el
+// eco pueda pegarse en un ticket sin filtrar credenciales.
+func (s *ServidorHTTP) manejarDiagnostico(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		enviarRespuestaJSON(w, http.StatusMethodNotAllowed, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "Método no permitido",
+			CodigoError: CodigoMetodoNoPermitido,
+		})
+		return
+	}
+
+	cabeceras := make(map[string]string, len(r.Header))
+	for nombre, valores := range r.Header {
+		if strings.EqualFold(nombre, "Authorization") {
+			cabeceras[nombre] = "[REDACTADO]"
+			continue
+		}
+		cabeceras[nombre] = strings.Join(valores, ", ")
+	}
+
+	enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+		Exitoso: true,
+		Mensaje: "Eco de la petición recibida",
+		Datos: map[string]interface{}{
+			"metodo":           r.Method,
+			"cabeceras":        cabeceras,
+			"ip_remota":        s.ipRemotaDetectada(r),
+			"direccion_remota": r.RemoteAddr,
+			"esquema":          s.esquemaDetectado(r),
+			"host":             r.Host,
+		},
+	})
+}
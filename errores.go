@@ -0,0 +1,29 @@
+package main
+
+// Códigos de error estables de la API. Acompañan al mensaje legible en
+// RespuestaJSON.CodigoError para que los clientes puedan ramificar por
+// código sin depender del texto en español, que puede cambiar.
+const (
+	CodigoCamposObligatorios    = "CAMPOS_OBLIGATORIOS"
+	CodigoCuerpoDemasiadoGrande = "CUERPO_DEMASIADO_GRANDE"
+	CodigoCuerpoInvalido        = "CUERPO_INVALIDO"
+	CodigoEmailDesechable       = "EMAIL_DESECHABLE"
+	CodigoEmailDuplicado        = "EMAIL_DUPLICADO"
+	CodigoEmailInvalido         = "EMAIL_INVALIDO"
+	CodigoEmailSinMX            = "EMAIL_SIN_MX"
+	CodigoErrorInterno          = "ERROR_INTERNO"
+	CodigoFormatoNoSoportado    = "FORMATO_NO_SOPORTADO"
+	CodigoHostNoPermitido       = "HOST_NO_PERMITIDO"
+	CodigoLimiteTasaExcedido    = "LIMITE_TASA_EXCEDIDO"
+	CodigoLimiteUsuarios        = "LIMITE_USUARIOS"
+	CodigoMetadatosInvalidos    = "METADATOS_INVALIDOS"
+	CodigoMetodoNoPermitido     = "METODO_NO_PERMITIDO"
+	CodigoNoAutorizado          = "NO_AUTORIZADO"
+	CodigoNombreDuplicado       = "NOMBRE_DUPLICADO"
+	CodigoNombreInvalido        = "NOMBRE_INVALIDO"
+	CodigoParametroInvalido     = "PARAMETRO_INVALIDO"
+	CodigoRutaNoEncontrada      = "RUTA_NO_ENCONTRADA"
+	CodigoServicioNoDisponible  = "SERVICIO_NO_DISPONIBLE"
+	CodigoTiempoEsperaAgotado   = "TIEMPO_ESPERA_AGOTADO"
+	CodigoUsuarioNoEncontrado   = "USUARIO_NO_ENCONTRADO"
+)
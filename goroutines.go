@@ -0,0 +1,21 @@
+package main
+
+import (
+	"log"
+	"runtime/debug"
+)
+
+// irSeguro lanza fn en una goroutine protegida con recover. El middleware
+// de recuperación solo cubre goroutines de petición; cualquier trabajo de
+// fondo (webhooks, persistencia diferida, reintentos) debe lanzarse con
+// este helper para que un pánico no tumbe el proceso.
+func irSeguro(fn func()) {
+	go func() {
+		defer func() {
+			if causa := recover(); causa != nil {
+				log.Printf("Pánico recuperado en goroutine de fondo: %v\n%s", causa, debug.Stack())
+			}
+		}()
+		fn()
+	}()
+}
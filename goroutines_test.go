@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIrSeguroRecuperaPanico(t *testing.T) {
+	terminado := make(chan struct{})
+	irSeguro(func() {
+		defer close(terminado)
+		panic("explosión controlada")
+	})
+
+	select {
+	case <-terminado:
+		// El pánico se recuperó y el proceso sigue vivo.
+	case <-time.After(time.Second):
+		t.Fatal("la goroutine nunca terminó")
+	}
+
+	// Una segunda goroutine normal debe seguir funcionando.
+	resultado := make(chan int, 1)
+	irSeguro(func() { resultado <- 42 })
+	select {
+	case valor := <-resultado:
+		if valor != 42 {
+			t.Fatalf("valor inesperado: %d", valor)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("la goroutine posterior no se ejecutó")
+	}
+}
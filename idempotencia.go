@@ -0,0 +1,158 @@
+package main
+
+import (
+	"container/list"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Dimensiones de la caché de idempotencia del servidor: vigencia por
+// defecto de cada clave y tope de entradas para acotar la memoria.
+const (
+	vigenciaIdempotenciaPorDefecto  = time.Hour
+	capacidadIdempotenciaPorDefecto = 1024
+)
+
+// respuestaCacheada es la respuesta original de un POST, guardada para
+// repetirla ante reintentos con la misma Idempotency-Key.
+type respuestaCacheada struct {
+	Codigo    int
+	Cabeceras http.Header
+	Cuerpo    []byte
+}
+
+// entradaIdempotencia es el valor almacenado en la LRU.
+type entradaIdempotencia struct {
+	clave  string
+	valor  respuestaCacheada
+	creada time.Time
+}
+
+// cacheIdempotencia es una caché LRU thread-safe con TTL. El límite de
+// entradas acota la memoria aunque lleguen muchas claves distintas antes
+// de que el TTL las expire.
+type cacheIdempotencia struct {
+	mutex     sync.Mutex
+	capacidad int
+	ttl       time.Duration
+	orden     *list.List // la más reciente al frente
+	entradas  map[string]*list.Element
+}
+
+func nuevaCacheIdempotencia(capacidad int, ttl time.Duration) *cacheIdempotencia {
+	return &cacheIdempotencia{
+		capacidad: capacidad,
+		ttl:       ttl,
+		orden:     list.New(),
+		entradas:  map[string]*list.Element{},
+	}
+}
+
+// guardar inserta o actualiza la clave, expulsando la entrada menos usada
+// si se supera la capacidad.
+func (c *cacheIdempotencia) guardar(clave string, valor respuestaCacheada) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	if elemento, existe := c.entradas[clave]; existe {
+		entrada := elemento.Value.(*entradaIdempotencia)
+		entrada.valor = valor
+		entrada.creada = time.Now()
+		c.orden.MoveToFront(elemento)
+		return
+	}
+
+	c.entradas[clave] = c.orden.PushFront(&entradaIdempotencia{
+		clave:  clave,
+		valor:  valor,
+		creada: time.Now(),
+	})
+
+	for c.capacidad > 0 && c.orden.Len() > c.capacidad {
+		ultimo := c.orden.Back()
+		c.orden.Remove(ultimo)
+		delete(c.entradas, ultimo.Value.(*entradaIdempotencia).clave)
+	}
+}
+
+// obtener devuelve la respuesta cacheada si existe y no ha expirado,
+// marcándola como usada recientemente.
+func (c *cacheIdempotencia) obtener(clave string) (respuestaCacheada, bool) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	elemento, existe := c.entradas[clave]
+	if !existe {
+		return respuestaCacheada{}, false
+	}
+
+	entrada := elemento.Value.(*entradaIdempotencia)
+	if c.ttl > 0 && time.Since(entrada.creada) > c.ttl {
+		c.orden.Remove(elemento)
+		delete(c.entradas, clave)
+		return respuestaCacheada{}, false
+	}
+
+	c.orden.MoveToFront(elemento)
+	return entrada.valor, true
+}
+
+// longitud devuelve cuántas entradas hay actualmente en la caché.
+func (c *cacheIdempotencia) longitud() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.orden.Len()
+}
+
+// grabadoraDeRespuesta deja pasar la respuesta al cliente mientras copia
+// código y cuerpo, para poder repetirla ante un reintento con la misma
+// clave.
+type grabadoraDeRespuesta struct {
+	http.ResponseWriter
+	codigo int
+	cuerpo []byte
+}
+
+func (g *grabadoraDeRespuesta) WriteHeader(codigo int) {
+	g.codigo = codigo
+	g.ResponseWriter.WriteHeader(codigo)
+}
+
+func (g *grabadoraDeRespuesta) Write(datos []byte) (int, error) {
+	g.cuerpo = append(g.cuerpo, datos...)
+	return g.ResponseWriter.Write(datos)
+}
+
+// conIdempotencia atiende el header Idempotency-Key en los POST de
+// creación: el primer intento con una clave se procesa normal y su
+// respuesta queda en la caché; los reintentos con la misma clave reciben
+// esa respuesta tal cual (mismo código, Location y cuerpo) sin crear
+// nada más, marcados con Idempotency-Replayed por si el cliente quiere
+// distinguirlos. Sin el header no cambia nada.
+func (s *ServidorHTTP) conIdempotencia(w http.ResponseWriter, r *http.Request, manejar http.HandlerFunc) {
+	clave := strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	if clave == "" {
+		manejar(w, r)
+		return
+	}
+
+	if recordada, existe := s.idempotencia.obtener(clave); existe {
+		for nombre, valores := range recordada.Cabeceras {
+			w.Header()[nombre] = valores
+		}
+		w.Header().Set("Idempotency-Replayed", "true")
+		w.WriteHeader(recordada.Codigo)
+		_, _ = w.Write(recordada.Cuerpo)
+		return
+	}
+
+	grabadora := &grabadoraDeRespuesta{ResponseWriter: w, codigo: http.StatusOK}
+	manejar(grabadora, r)
+	s.idempotencia.guardar(clave, respuestaCacheada{
+		Codigo:    grabadora.codigo,
+		Cabeceras: grabadora.Header().Clone(),
+		Cuerpo:    grabadora.cuerpo,
+	})
+}
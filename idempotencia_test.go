@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheIdempotenciaExpulsaLasMasAntiguas(t *testing.T) {
+	cache := nuevaCacheIdempotencia(3, time.Minute)
+
+	for i := 1; i <= 5; i++ {
+		cache.guardar(fmt.Sprintf("clave-%d", i), respuestaCacheada{Codigo: 200 + i})
+	}
+
+	if cache.longitud() != 3 {
+		t.Fatalf("la caché debería mantener 3 entradas, tiene %d", cache.longitud())
+	}
+	for i := 1; i <= 2; i++ {
+		if _, existe := cache.obtener(fmt.Sprintf("clave-%d", i)); existe {
+			t.Fatalf("clave-%d debería haberse expulsado", i)
+		}
+	}
+	for i := 3; i <= 5; i++ {
+		if _, existe := cache.obtener(fmt.Sprintf("clave-%d", i)); !existe {
+			t.Fatalf("clave-%d debería seguir en la caché", i)
+		}
+	}
+}
+
+func TestCacheIdempotenciaRespetaUsoReciente(t *testing.T) {
+	cache := nuevaCacheIdempotencia(2, time.Minute)
+	cache.guardar("a", respuestaCacheada{Codigo: 201})
+	cache.guardar("b", respuestaCacheada{Codigo: 201})
+
+	// Tocar "a" la convierte en la más reciente; insertar "c" expulsa "b".
+	cache.obtener("a")
+	cache.guardar("c", respuestaCacheada{Codigo: 201})
+
+	if _, existe := cache.obtener("b"); existe {
+		t.Fatal("\"b\" debería haberse expulsado por ser la menos usada")
+	}
+	if _, existe := cache.obtener("a"); !existe {
+		t.Fatal("\"a\" debería seguir en la caché tras usarse")
+	}
+}
+
+func TestCacheIdempotenciaTTL(t *testing.T) {
+	cache := nuevaCacheIdempotencia(10, 20*time.Millisecond)
+	cache.guardar("efimera", respuestaCacheada{Codigo: 201})
+
+	time.Sleep(30 * time.Millisecond)
+	if _, existe := cache.obtener("efimera"); existe {
+		t.Fatal("la entrada debería haber expirado por TTL")
+	}
+}
+
+func TestPOSTConIdempotencyKeyNoDuplica(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	enviar := func(clave string) *httptest.ResponseRecorder {
+		t.Helper()
+		cuerpo := strings.NewReader(`{"nombre":"Ana","email":"ana@ejemplo.com"}`)
+		peticion := httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo)
+		peticion.Header.Set("Idempotency-Key", clave)
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarios(grabadora, peticion)
+		return grabadora
+	}
+
+	primera := enviar("reintento-123")
+	if primera.Code != http.StatusCreated {
+		t.Fatalf("código esperado 201, obtenido %d: %s", primera.Code, primera.Body.String())
+	}
+
+	segunda := enviar("reintento-123")
+	if segunda.Code != http.StatusCreated {
+		t.Fatalf("el reintento debería repetir el 201, obtenido %d", segunda.Code)
+	}
+	if segunda.Body.String() != primera.Body.String() {
+		t.Fatalf("las respuestas deberían ser idénticas:\n%s\n%s", primera.Body.String(), segunda.Body.String())
+	}
+	if segunda.Header().Get("Location") != primera.Header().Get("Location") {
+		t.Fatalf("el Location debería repetirse: %q vs %q", primera.Header().Get("Location"), segunda.Header().Get("Location"))
+	}
+	if segunda.Header().Get("Idempotency-Replayed") != "true" {
+		t.Fatal("el reintento debería marcarse como repetido")
+	}
+
+	total, err := servidor.almacen.Contar()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 1 {
+		t.Fatalf("debería existir un solo usuario, hay %d", total)
+	}
+}
+
+func TestPOSTConClavesDistintasSigueValidando(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	enviar := func(clave string) *httptest.ResponseRecorder {
+		t.Helper()
+		cuerpo := strings.NewReader(`{"nombre":"Ana","email":"ana@ejemplo.com"}`)
+		peticion := httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo)
+		if clave != "" {
+			peticion.Header.Set("Idempotency-Key", clave)
+		}
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarios(grabadora, peticion)
+		return grabadora
+	}
+
+	if codigo := enviar("clave-a").Code; codigo != http.StatusCreated {
+		t.Fatalf("código esperado 201, obtenido %d", codigo)
+	}
+	// Otra clave no reutiliza la respuesta: el duplicado real da 409, y
+	// ese 409 también queda recordado para sus propios reintentos.
+	if codigo := enviar("clave-b").Code; codigo != http.StatusConflict {
+		t.Fatalf("con otra clave el email duplicado debería dar 409, dio %d", codigo)
+	}
+	if repetida := enviar("clave-b"); repetida.Code != http.StatusConflict || repetida.Header().Get("Idempotency-Replayed") != "true" {
+		t.Fatalf("el reintento del 409 debería repetirse tal cual: %d", repetida.Code)
+	}
+}
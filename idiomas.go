@@ -0,0 +1,77 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+)
+
+// El API responde en español por defecto; con Accept-Language: en los
+// mensajes estándar se traducen al inglés. Los códigos de error estables
+// (CodigoError) no se tocan nunca: la lógica de cliente depende de ellos.
+
+// traduccionesEN mapea los mensajes y errores estándar del API a su
+// versión en inglés. Los mensajes construidos con datos dinámicos no se
+// traducen y salen en español.
+var traduccionesEN = map[string]string{
+	"saludable":                                       "healthy",
+	"listo":                                           "ready",
+	"Método no permitido":                             "Method not allowed",
+	"Ruta no encontrada":                              "Route not found",
+	"Usuario no encontrado":                           "User not found",
+	"Usuario encontrado":                              "User found",
+	"Usuario creado correctamente":                    "User created successfully",
+	"Usuario actualizado correctamente":               "User updated successfully",
+	"Error interno del servidor":                      "Internal server error",
+	"El nombre y el email son obligatorios":           "Name and email are required",
+	"El email no tiene un formato válido":             "The email format is not valid",
+	"No se aceptan emails de dominios desechables":    "Disposable email domains are not accepted",
+	"Límite de peticiones excedido":                   "Rate limit exceeded",
+	"Token inválido":                                  "Invalid token",
+	"El almacenamiento todavía no está listo":         "Storage is not ready yet",
+	"Estado del servidor":                             "Server status",
+	"Conteo de usuarios":                              "User count",
+	"La operación excedió el tiempo de espera":        "The operation timed out",
+	"Content-Type no soportado; use application/json": "Unsupported Content-Type; use application/json",
+}
+
+// idiomaPreferido elige el idioma de respuesta a partir de
+// Accept-Language: el primer tag reconocido gana, ignorando los factores
+// de calidad; sin header (o sin tag reconocido) se responde en español.
+func idiomaPreferido(r *http.Request) string {
+	for _, tag := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, _, _ = strings.Cut(strings.TrimSpace(tag), ";")
+		tag = strings.ToLower(tag)
+		switch {
+		case tag == "es" || strings.HasPrefix(tag, "es-"):
+			return "es"
+		case tag == "en" || strings.HasPrefix(tag, "en-"):
+			return "en"
+		}
+	}
+	return "es"
+}
+
+// traducirRespuesta localiza los campos Mensaje y Error de la respuesta
+// al idioma dado; los textos sin traducción quedan tal cual.
+func traducirRespuesta(respuesta RespuestaJSON, idioma string) RespuestaJSON {
+	if idioma != "en" {
+		return respuesta
+	}
+	if traducido, existe := traduccionesEN[respuesta.Mensaje]; existe {
+		respuesta.Mensaje = traducido
+	}
+	if traducido, existe := traduccionesEN[respuesta.Error]; existe {
+		respuesta.Error = traducido
+	}
+	return respuesta
+}
+
+// MiddlewareIdioma anuncia en Content-Language el idioma elegido según
+// Accept-Language. enviarRespuestaJSON lo lee de la respuesta para
+// traducir los mensajes, de modo que los handlers no cambian.
+func MiddlewareIdioma(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Language", idiomaPreferido(r))
+		siguiente.ServeHTTP(w, r)
+	})
+}
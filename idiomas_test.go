@@ -0,0 +1,75 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRespuestasLocalizadasPorAcceptLanguage(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	manejador := servidor.construirManejador()
+
+	pedirSalud := func(idioma string) *httptest.ResponseRecorder {
+		peticion := httptest.NewRequest(http.MethodGet, "/salud", nil)
+		if idioma != "" {
+			peticion.Header.Set("Accept-Language", idioma)
+		}
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora
+	}
+
+	t.Run("sin header responde en español", func(t *testing.T) {
+		grabadora := pedirSalud("")
+		if !strings.Contains(grabadora.Body.String(), `"mensaje":"saludable"`) {
+			t.Fatalf("se esperaba el mensaje en español: %s", grabadora.Body.String())
+		}
+		if idioma := grabadora.Header().Get("Content-Language"); idioma != "es" {
+			t.Fatalf("Content-Language esperado es, fue %q", idioma)
+		}
+	})
+
+	t.Run("con en responde en inglés", func(t *testing.T) {
+		grabadora := pedirSalud("en")
+		if !strings.Contains(grabadora.Body.String(), `"mensaje":"healthy"`) {
+			t.Fatalf("se esperaba el mensaje en inglés: %s", grabadora.Body.String())
+		}
+		if idioma := grabadora.Header().Get("Content-Language"); idioma != "en" {
+			t.Fatalf("Content-Language esperado en, fue %q", idioma)
+		}
+	})
+
+	t.Run("los tags regionales y los factores de calidad se entienden", func(t *testing.T) {
+		grabadora := pedirSalud("en-US;q=0.9, fr;q=0.8")
+		if !strings.Contains(grabadora.Body.String(), `"mensaje":"healthy"`) {
+			t.Fatalf("en-US debería tratarse como inglés: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("un idioma desconocido cae al español", func(t *testing.T) {
+		grabadora := pedirSalud("fr")
+		if !strings.Contains(grabadora.Body.String(), `"mensaje":"saludable"`) {
+			t.Fatalf("sin idioma reconocido debería responder en español: %s", grabadora.Body.String())
+		}
+	})
+}
+
+func TestTraduccionConservaElCodigoDeError(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	manejador := servidor.construirManejador()
+
+	peticion := httptest.NewRequest(http.MethodPost, "/diagnostico", nil)
+	peticion.Header.Set("Accept-Language", "en")
+	grabadora := httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, peticion)
+
+	cuerpo := grabadora.Body.String()
+	if !strings.Contains(cuerpo, "Method not allowed") {
+		t.Fatalf("el error debería salir en inglés: %s", cuerpo)
+	}
+	if !strings.Contains(cuerpo, CodigoMetodoNoPermitido) {
+		t.Fatalf("el código de error estable no debe traducirse: %s", cuerpo)
+	}
+}
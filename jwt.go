@@ -0,0 +1,138 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// claveJWT es un secreto de firma. La clave actual no expira; las
+// anteriores se aceptan hasta que venza su ventana de gracia.
+type claveJWT struct {
+	secreto      []byte
+	expiraGracia time.Time // cero para la clave actual
+}
+
+// rotadorJWT mantiene la clave de firma actual y las anteriores todavía
+// aceptadas, permitiendo rotar el secreto sin invalidar tokens activos.
+type rotadorJWT struct {
+	mutex  sync.RWMutex
+	claves []claveJWT // la actual primero
+}
+
+func nuevoRotadorJWT(secreto string) *rotadorJWT {
+	return &rotadorJWT{claves: []claveJWT{{secreto: []byte(secreto)}}}
+}
+
+// rotar convierte el secreto dado en el actual y concede a las claves
+// anteriores una ventana de gracia durante la cual sus tokens siguen
+// siendo válidos.
+func (r *rotadorJWT) rotar(nuevoSecreto string, gracia time.Duration) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	vencimiento := time.Now().Add(gracia)
+	for i := range r.claves {
+		if r.claves[i].expiraGracia.IsZero() {
+			r.claves[i].expiraGracia = vencimiento
+		}
+	}
+	r.claves = append([]claveJWT{{secreto: []byte(nuevoSecreto)}}, r.claves...)
+}
+
+func codificarSegmento(datos []byte) string {
+	return base64.RawURLEncoding.EncodeToString(datos)
+}
+
+func firmarHS256(mensaje string, secreto []byte) string {
+	mac := hmac.New(sha256.New, secreto)
+	mac.Write([]byte(mensaje))
+	return codificarSegmento(mac.Sum(nil))
+}
+
+// firmar emite un token HS256 compacto con la clave actual.
+func (r *rotadorJWT) firmar(carga map[string]interface{}) (string, error) {
+	cabecera := codificarSegmento([]byte(`{"alg":"HS256","typ":"JWT"}`))
+	cuerpo, err := json.Marshal(carga)
+	if err != nil {
+		return "", fmt.Errorf("error al serializar la carga: %w", err)
+	}
+
+	r.mutex.RLock()
+	secreto := r.claves[0].secreto
+	r.mutex.RUnlock()
+
+	mensaje := cabecera + "." + codificarSegmento(cuerpo)
+	return mensaje + "." + firmarHS256(mensaje, secreto), nil
+}
+
+// verificar valida la firma del token probando las claves en orden: la
+// actual primero y después las anteriores cuya gracia no haya vencido.
+func (r *rotadorJWT) verificar(token string) (map[string]interface{}, error) {
+	partes := strings.Split(token, ".")
+	if len(partes) != 3 {
+		return nil, errors.New("el token no tiene tres segmentos")
+	}
+	mensaje := partes[0] + "." + partes[1]
+
+	r.mutex.RLock()
+	defer r.mutex.RUnlock()
+
+	ahora := time.Now()
+	for _, clave := range r.claves {
+		if !clave.expiraGracia.IsZero() && ahora.After(clave.expiraGracia) {
+			continue
+		}
+		if hmac.Equal([]byte(firmarHS256(mensaje, clave.secreto)), []byte(partes[2])) {
+			cuerpo, err := base64.RawURLEncoding.DecodeString(partes[1])
+			if err != nil {
+				return nil, fmt.Errorf("carga ilegible: %w", err)
+			}
+			carga := map[string]interface{}{}
+			if err := json.Unmarshal(cuerpo, &carga); err != nil {
+				return nil, fmt.Errorf("carga ilegible: %w", err)
+			}
+			return carga, nil
+		}
+	}
+	return nil, errors.New("firma inválida con todas las claves vigentes")
+}
+
+// MiddlewareJWT exige un token Bearer firmado con alguna clave vigente del
+// rotador, dejando pasar las rutas públicas de salud y raíz.
+func MiddlewareJWT(rotador *rotadorJWT, siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/", "/salud", "/listo":
+			siguiente.ServeHTTP(w, r)
+			return
+		}
+
+		token, encontrado := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !encontrado {
+			enviarRespuestaJSON(w, http.StatusUnauthorized, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "Falta el token Bearer",
+				CodigoError: CodigoNoAutorizado,
+			})
+			return
+		}
+		if _, err := rotador.verificar(token); err != nil {
+			enviarRespuestaJSON(w, http.StatusUnauthorized, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "Token inválido",
+				CodigoError: CodigoNoAutorizado,
+			})
+			return
+		}
+
+		siguiente.ServeHTTP(w, r)
+	})
+}
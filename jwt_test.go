@@ -0,0 +1,74 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestRotacionDeSecretoJWTConGracia(t *testing.T) {
+	rotador := nuevoRotadorJWT("secreto-viejo")
+	tokenViejo, err := rotador.firmar(map[string]interface{}{"sub": "ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	rotador.rotar("secreto-nuevo", 80*time.Millisecond)
+
+	tokenNuevo, err := rotador.firmar(map[string]interface{}{"sub": "ana"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := rotador.verificar(tokenNuevo); err != nil {
+		t.Fatalf("el token de la clave actual debería validar: %v", err)
+	}
+
+	carga, err := rotador.verificar(tokenViejo)
+	if err != nil {
+		t.Fatalf("el token de la clave anterior debería validar durante la gracia: %v", err)
+	}
+	if carga["sub"] != "ana" {
+		t.Fatalf("carga inesperada: %v", carga)
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if _, err := rotador.verificar(tokenViejo); err == nil {
+		t.Fatal("pasada la gracia, el token viejo debería rechazarse")
+	}
+	if _, err := rotador.verificar(tokenNuevo); err != nil {
+		t.Fatalf("la clave actual no expira: %v", err)
+	}
+}
+
+func TestMiddlewareJWT(t *testing.T) {
+	rotador := nuevoRotadorJWT("secreto")
+	manejador := MiddlewareJWT(rotador, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	pedir := func(ruta, token string) int {
+		peticion := httptest.NewRequest(http.MethodGet, ruta, nil)
+		if token != "" {
+			peticion.Header.Set("Authorization", "Bearer "+token)
+		}
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora.Code
+	}
+
+	if codigo := pedir("/salud", ""); codigo != http.StatusOK {
+		t.Fatalf("/salud es pública, dio %d", codigo)
+	}
+	if codigo := pedir("/usuarios", ""); codigo != http.StatusUnauthorized {
+		t.Fatalf("sin token debería dar 401, dio %d", codigo)
+	}
+	if codigo := pedir("/usuarios", "token.falso.aqui"); codigo != http.StatusUnauthorized {
+		t.Fatalf("token inválido debería dar 401, dio %d", codigo)
+	}
+
+	token, _ := rotador.firmar(map[string]interface{}{"sub": "ana"})
+	if codigo := pedir("/usuarios", token); codigo != http.StatusOK {
+		t.Fatalf("token válido debería dar 200, dio %d", codigo)
+	}
+}
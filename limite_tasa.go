@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ventanaTasa acumula las peticiones de una identidad dentro de la ventana
+// de tiempo actual.
+type ventanaTasa struct {
+	inicio time.Time
+	cuenta int
+}
+
+// limitadorTasa implementa un límite de peticiones por ventana fija y por
+// identidad (normalmente la IP del cliente).
+type limitadorTasa struct {
+	limite  int
+	ventana time.Duration
+
+	// confiarEnXFF usa la primera IP de X-Forwarded-For como identidad,
+	// para despliegues detrás de un proxy de confianza.
+	confiarEnXFF bool
+
+	mutex       sync.Mutex
+	visitas     map[string]*ventanaTasa
+	ultimaPurga time.Time
+}
+
+func nuevoLimitadorTasa(limite int, ventana time.Duration) *limitadorTasa {
+	return &limitadorTasa{
+		limite:      limite,
+		ventana:     ventana,
+		visitas:     map[string]*ventanaTasa{},
+		ultimaPurga: time.Now(),
+	}
+}
+
+// purgarInactivas elimina los buckets cuya ventana ya venció para que el
+// mapa no crezca sin límite con IPs que no vuelven. Debe llamarse con el
+// mutex tomado.
+func (l *limitadorTasa) purgarInactivas(ahora time.Time) {
+	for identidad, visita := range l.visitas {
+		if ahora.Sub(visita.inicio) >= l.ventana {
+			delete(l.visitas, identidad)
+		}
+	}
+	l.ultimaPurga = ahora
+}
+
+// permitir registra una petición de la identidad dada y devuelve si está
+// dentro de cuota, cuántas peticiones le quedan y cuándo se reinicia la
+// ventana.
+func (l *limitadorTasa) permitir(identidad string) (permitida bool, restantes int, reinicio time.Time) {
+	ahora := time.Now()
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if ahora.Sub(l.ultimaPurga) >= l.ventana {
+		l.purgarInactivas(ahora)
+	}
+
+	visita, existe := l.visitas[identidad]
+	if !existe || ahora.Sub(visita.inicio) >= l.ventana {
+		visita = &ventanaTasa{inicio: ahora}
+		l.visitas[identidad] = visita
+	}
+
+	reinicio = visita.inicio.Add(l.ventana)
+	if visita.cuenta >= l.limite {
+		return false, 0, reinicio
+	}
+	visita.cuenta++
+	return true, l.limite - visita.cuenta, reinicio
+}
+
+// identidadCliente extrae la identidad usada para el límite de tasa: la IP
+// remota sin el puerto o, si se confía en el proxy, la primera IP de
+// X-Forwarded-For.
+func (l *limitadorTasa) identidadCliente(r *http.Request) string {
+	if l.confiarEnXFF {
+		if reenviada := r.Header.Get("X-Forwarded-For"); reenviada != "" {
+			primera, _, _ := strings.Cut(reenviada, ",")
+			if ip := strings.TrimSpace(primera); ip != "" {
+				return ip
+			}
+		}
+	}
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
+
+// MiddlewareLimiteTasa limita las peticiones por IP y anuncia la cuota en
+// las cabeceras X-RateLimit-Limit, X-RateLimit-Remaining y
+// X-RateLimit-Reset para que los clientes se autorregulen. Los 429 llevan
+// además Retry-After con los segundos hasta el reinicio de la ventana.
+func MiddlewareLimiteTasa(limitador *limitadorTasa, siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		permitida, restantes, reinicio := limitador.permitir(limitador.identidadCliente(r))
+
+		w.Header().Set("X-RateLimit-Limit", fmt.Sprintf("%d", limitador.limite))
+		w.Header().Set("X-RateLimit-Remaining", fmt.Sprintf("%d", restantes))
+		w.Header().Set("X-RateLimit-Reset", fmt.Sprintf("%d", reinicio.Unix()))
+
+		if !permitida {
+			segundos := int(time.Until(reinicio).Seconds() + 0.999)
+			if segundos < 1 {
+				segundos = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(segundos))
+			enviarRespuestaJSON(w, http.StatusTooManyRequests, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "Límite de peticiones excedido",
+				CodigoError: CodigoLimiteTasaExcedido,
+			})
+			return
+		}
+
+		siguiente.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareLimiteTasaCabecerasDeCuota(t *testing.T) {
+	limitador := nuevoLimitadorTasa(3, 100*time.Millisecond)
+	manejador := MiddlewareLimiteTasa(limitador, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	pedir := func() *httptest.ResponseRecorder {
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+		peticion.RemoteAddr = "10.0.0.1:54321"
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora
+	}
+
+	for esperadas := 2; esperadas >= 0; esperadas-- {
+		grabadora := pedir()
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("petición dentro de cuota rechazada con %d", grabadora.Code)
+		}
+		if limite := grabadora.Header().Get("X-RateLimit-Limit"); limite != "3" {
+			t.Fatalf("X-RateLimit-Limit esperado 3, obtenido %q", limite)
+		}
+		if restantes := grabadora.Header().Get("X-RateLimit-Remaining"); restantes != strconv.Itoa(esperadas) {
+			t.Fatalf("X-RateLimit-Remaining esperado %d, obtenido %q", esperadas, restantes)
+		}
+	}
+
+	grabadora := pedir()
+	if grabadora.Code != http.StatusTooManyRequests {
+		t.Fatalf("la cuarta petición debería dar 429, dio %d", grabadora.Code)
+	}
+	if reinicio := grabadora.Header().Get("X-RateLimit-Reset"); reinicio == "" {
+		t.Fatal("falta la cabecera X-RateLimit-Reset")
+	}
+	if espera := grabadora.Header().Get("Retry-After"); espera == "" {
+		t.Fatal("falta la cabecera Retry-After en el 429")
+	} else if segundos, err := strconv.Atoi(espera); err != nil || segundos < 1 {
+		t.Fatalf("Retry-After debería ser un entero positivo, es %q", espera)
+	}
+
+	// Al vencer la ventana la cuota se reinicia.
+	time.Sleep(110 * time.Millisecond)
+	grabadora = pedir()
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("tras vencer la ventana debería aceptar, dio %d", grabadora.Code)
+	}
+	if restantes := grabadora.Header().Get("X-RateLimit-Remaining"); restantes != "2" {
+		t.Fatalf("tras el reinicio se esperaban 2 restantes, obtenido %q", restantes)
+	}
+}
+
+func TestIdentidadClienteConXForwardedFor(t *testing.T) {
+	peticion := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+	peticion.RemoteAddr = "10.0.0.1:54321"
+	peticion.Header.Set("X-Forwarded-For", "203.0.113.7, 10.0.0.1")
+
+	sinConfianza := nuevoLimitadorTasa(1, time.Minute)
+	if identidad := sinConfianza.identidadCliente(peticion); identidad != "10.0.0.1" {
+		t.Fatalf("sin confianza en el proxy debería usarse RemoteAddr, usó %q", identidad)
+	}
+
+	conConfianza := nuevoLimitadorTasa(1, time.Minute)
+	conConfianza.confiarEnXFF = true
+	if identidad := conConfianza.identidadCliente(peticion); identidad != "203.0.113.7" {
+		t.Fatalf("con confianza debería usarse la primera IP de X-Forwarded-For, usó %q", identidad)
+	}
+}
+
+func TestLimitadorTasaPurgaBucketsInactivos(t *testing.T) {
+	limitador := nuevoLimitadorTasa(5, 10*time.Millisecond)
+	limitador.permitir("10.0.0.1")
+	limitador.permitir("10.0.0.2")
+
+	time.Sleep(15 * time.Millisecond)
+	limitador.permitir("10.0.0.3")
+
+	limitador.mutex.Lock()
+	defer limitador.mutex.Unlock()
+	if len(limitador.visitas) != 1 {
+		t.Fatalf("los buckets vencidos deberían purgarse, quedan %d", len(limitador.visitas))
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"log"
+	"os"
+)
+
+func main() {
+	if len(os.Args) > 1 && os.Args[1] == "cliente" {
+		ejemploCompletoDeUso()
+		return
+	}
+
+	config, err := cargarConfiguracion(os.Getenv("RUTA_CONFIGURACION"))
+	if err != nil {
+		log.Fatalf("Configuración inválida: %v", err)
+	}
+
+	almacen, cerrar, err := abrirAlmacen(config)
+	if err != nil {
+		log.Fatalf("No se pudo abrir el almacenamiento: %v", err)
+	}
+	if cerrar != nil {
+		defer cerrar()
+	}
+
+	servidor := NuevoServidorConAlmacen(config, almacen)
+	if err := servidor.IniciarServidor(); err != nil {
+		log.Fatalf("El servidor terminó con error: %v", err)
+	}
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// escritorConEstado envuelve el ResponseWriter para capturar el código de
+// estado que escribe el handler. Si nunca llama a WriteHeader se asume 200.
+type escritorConEstado struct {
+	http.ResponseWriter
+	codigo int
+}
+
+func (e *escritorConEstado) WriteHeader(codigo int) {
+	e.codigo = codigo
+	e.ResponseWriter.WriteHeader(codigo)
+}
+
+// Flush delega en el writer interno para no romper respuestas en streaming.
+func (e *escritorConEstado) Flush() {
+	if f, ok := e.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// limitesCubetas son los límites superiores (en segundos) del histograma
+// de duración, al estilo de los buckets por defecto de Prometheus.
+var limitesCubetas = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5}
+
+// acumuladorMetricas agrega contadores y latencias de las peticiones
+// atendidas. Es la única fuente de métricas; cualquier formato de
+// exposición debe leer de aquí.
+type acumuladorMetricas struct {
+	mutex           sync.Mutex
+	porMetodoCodigo map[string]int64
+	total           int64
+	errores         int64 // respuestas 5xx
+	duracionTotal   time.Duration
+	duracionMaxima  time.Duration
+
+	// cubetas lleva el conteo acumulado por límite de limitesCubetas,
+	// con la semántica "le" de un histograma Prometheus.
+	cubetas []int64
+}
+
+func nuevoAcumuladorMetricas() *acumuladorMetricas {
+	return &acumuladorMetricas{
+		porMetodoCodigo: map[string]int64{},
+		cubetas:         make([]int64, len(limitesCubetas)),
+	}
+}
+
+// registrar anota una petición completada.
+func (a *acumuladorMetricas) registrar(metodo string, codigo int, duracion time.Duration) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	a.porMetodoCodigo[fmt.Sprintf("%s %d", metodo, codigo)]++
+	a.total++
+	if codigo >= 500 {
+		a.errores++
+	}
+	a.duracionTotal += duracion
+	if duracion > a.duracionMaxima {
+		a.duracionMaxima = duracion
+	}
+	segundos := duracion.Seconds()
+	for i, limite := range limitesCubetas {
+		if segundos <= limite {
+			a.cubetas[i]++
+		}
+	}
+}
+
+// totalAtendidas devuelve el número de peticiones registradas hasta ahora.
+func (a *acumuladorMetricas) totalAtendidas() int64 {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+	return a.total
+}
+
+// instantanea devuelve una copia estructurada de todas las métricas.
+func (a *acumuladorMetricas) instantanea() map[string]interface{} {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	contadores := make(map[string]int64, len(a.porMetodoCodigo))
+	for clave, valor := range a.porMetodoCodigo {
+		contadores[clave] = valor
+	}
+
+	var promedioMS float64
+	var tasaError float64
+	if a.total > 0 {
+		promedioMS = float64(a.duracionTotal.Milliseconds()) / float64(a.total)
+		tasaError = float64(a.errores) / float64(a.total)
+	}
+
+	return map[string]interface{}{
+		"peticiones": map[string]interface{}{
+			"total":             a.total,
+			"por_metodo_codigo": contadores,
+			"errores_5xx":       a.errores,
+			"tasa_error":        tasaError,
+		},
+		"latencia": map[string]interface{}{
+			"promedio_ms": promedioMS,
+			"maxima_ms":   a.duracionMaxima.Milliseconds(),
+		},
+	}
+}
+
+// MiddlewareMetricas acumula contadores por método y código más la
+// duración de cada petición.
+func (s *ServidorHTTP) MiddlewareMetricas(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inicio := time.Now()
+		escritor := &escritorConEstado{ResponseWriter: w, codigo: http.StatusOK}
+		siguiente.ServeHTTP(escritor, r)
+		s.metricas.registrar(r.Method, escritor.codigo, time.Since(inicio))
+	})
+}
+
+// escribirPrometheus vuelca las métricas en el formato de exposición de
+// texto de Prometheus. Es una implementación mínima a propósito: no vale
+// la pena cargar con la librería oficial para cuatro series.
+func (a *acumuladorMetricas) escribirPrometheus(w io.Writer) {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	fmt.Fprintln(w, "# HELP peticiones_http_total Peticiones atendidas por método y código de estado.")
+	fmt.Fprintln(w, "# TYPE peticiones_http_total counter")
+	claves := make([]string, 0, len(a.porMetodoCodigo))
+	for clave := range a.porMetodoCodigo {
+		claves = append(claves, clave)
+	}
+	sort.Strings(claves)
+	for _, clave := range claves {
+		metodo, codigo, _ := strings.Cut(clave, " ")
+		fmt.Fprintf(w, "peticiones_http_total{metodo=%q,codigo=%q} %d\n", metodo, codigo, a.porMetodoCodigo[clave])
+	}
+
+	fmt.Fprintln(w, "# HELP duracion_peticion_segundos Duración de las peticiones atendidas.")
+	fmt.Fprintln(w, "# TYPE duracion_peticion_segundos histogram")
+	for i, limite := range limitesCubetas {
+		fmt.Fprintf(w, "duracion_peticion_segundos_bucket{le=%q} %d\n", strconv.FormatFloat(limite, 'g', -1, 64), a.cubetas[i])
+	}
+	fmt.Fprintf(w, "duracion_peticion_segundos_bucket{le=\"+Inf\"} %d\n", a.total)
+	fmt.Fprintf(w, "duracion_peticion_segundos_sum %g\n", a.duracionTotal.Seconds())
+	fmt.Fprintf(w, "duracion_peticion_segundos_count %d\n", a.total)
+}
+
+// manejarMetricas expone las métricas en el formato de texto de
+// Prometheus, incluyendo el gauge de usuarios en memoria.
+func (s *ServidorHTTP) manejarMetricas(w http.ResponseWriter, r *http.Request) {
+	// Un fallo del almacén no debe tumbar el scrape entero: el gauge
+	// queda en cero y el error va al log.
+	totalUsuarios, err := s.almacen.Contar()
+	if err != nil {
+		s.registrador.Error("error al contar usuarios para métricas", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.metricas.escribirPrometheus(w)
+	fmt.Fprintln(w, "# HELP usuarios_en_memoria Usuarios cargados actualmente en memoria.")
+	fmt.Fprintln(w, "# TYPE usuarios_en_memoria gauge")
+	fmt.Fprintf(w, "usuarios_en_memoria %d\n", totalUsuarios)
+}
+
+// manejarMetricasJSON expone todas las métricas en un único JSON
+// estructurado, conveniente para scripts que no parsean el formato
+// Prometheus.
+func (s *ServidorHTTP) manejarMetricasJSON(w http.ResponseWriter, r *http.Request) {
+	totalUsuarios, err := s.almacen.Contar()
+	if err != nil {
+		s.errorDeAlmacen(w, err)
+		return
+	}
+
+	var memoria runtime.MemStats
+	runtime.ReadMemStats(&memoria)
+
+	datos := s.metricas.instantanea()
+	datos["usuarios_en_memoria"] = totalUsuarios
+	datos["memoria"] = map[string]interface{}{
+		"heap_bytes":      memoria.HeapAlloc,
+		"total_asignados": memoria.TotalAlloc,
+		"num_gc":          memoria.NumGC,
+	}
+
+	enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+		Exitoso: true,
+		Mensaje: "Métricas del servidor",
+		Datos:   datos,
+	})
+}
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMetricasJSONIncluyeSeccionesClave(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	manejador := servidor.MiddlewareMetricas(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/falla" {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	for i := 0; i < 3; i++ {
+		manejador.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+	}
+	manejador.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/falla", nil))
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarMetricasJSON(grabadora, httptest.NewRequest(http.MethodGet, "/estado/metricas.json", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+	}
+
+	var respuesta struct {
+		Datos struct {
+			Peticiones struct {
+				Total           int64            `json:"total"`
+				PorMetodoCodigo map[string]int64 `json:"por_metodo_codigo"`
+				Errores         int64            `json:"errores_5xx"`
+				TasaError       float64          `json:"tasa_error"`
+			} `json:"peticiones"`
+			Latencia map[string]float64 `json:"latencia"`
+			Memoria  map[string]float64 `json:"memoria"`
+		} `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatalf("respuesta inválida: %v", err)
+	}
+
+	peticiones := respuesta.Datos.Peticiones
+	if peticiones.Total != 4 || peticiones.Errores != 1 {
+		t.Fatalf("contadores inesperados: %+v", peticiones)
+	}
+	if peticiones.PorMetodoCodigo["GET 200"] != 3 || peticiones.PorMetodoCodigo["GET 500"] != 1 {
+		t.Fatalf("desglose por método/código inesperado: %v", peticiones.PorMetodoCodigo)
+	}
+	if peticiones.TasaError != 0.25 {
+		t.Fatalf("tasa de error esperada 0.25, obtenida %v", peticiones.TasaError)
+	}
+	if respuesta.Datos.Memoria["heap_bytes"] <= 0 {
+		t.Fatalf("falta la sección de memoria: %v", respuesta.Datos.Memoria)
+	}
+}
+
+func TestEscritorConEstado(t *testing.T) {
+	t.Run("asume 200 sin WriteHeader explícito", func(t *testing.T) {
+		escritor := &escritorConEstado{ResponseWriter: httptest.NewRecorder(), codigo: http.StatusOK}
+		escritor.Write([]byte("hola"))
+		if escritor.codigo != http.StatusOK {
+			t.Fatalf("sin WriteHeader el código debería quedar en 200, quedó %d", escritor.codigo)
+		}
+	})
+
+	t.Run("captura el código escrito", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		escritor := &escritorConEstado{ResponseWriter: grabadora, codigo: http.StatusOK}
+		escritor.WriteHeader(http.StatusNotFound)
+		if escritor.codigo != http.StatusNotFound || grabadora.Code != http.StatusNotFound {
+			t.Fatalf("código no propagado: capturado %d, escrito %d", escritor.codigo, grabadora.Code)
+		}
+	})
+
+	t.Run("delega Flush para streaming", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		escritor := &escritorConEstado{ResponseWriter: grabadora, codigo: http.StatusOK}
+		var w http.ResponseWriter = escritor
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			t.Fatal("escritorConEstado debería implementar http.Flusher")
+		}
+		flusher.Flush()
+		if !grabadora.Flushed {
+			t.Fatal("Flush no llegó al writer interno")
+		}
+	})
+}
+
+func TestMetricasFormatoPrometheus(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	manejador := servidor.MiddlewareMetricas(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+	for i := 0; i < 2; i++ {
+		manejador.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+	}
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarMetricas(grabadora, httptest.NewRequest(http.MethodGet, "/metricas", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+	}
+	if tipo := grabadora.Header().Get("Content-Type"); !strings.HasPrefix(tipo, "text/plain") {
+		t.Fatalf("Content-Type inesperado: %q", tipo)
+	}
+
+	cuerpo := grabadora.Body.String()
+	esperadas := []string{
+		`peticiones_http_total{metodo="GET",codigo="200"} 2`,
+		`duracion_peticion_segundos_bucket{le="+Inf"} 2`,
+		"duracion_peticion_segundos_count 2",
+		"usuarios_en_memoria 1",
+		"# TYPE duracion_peticion_segundos histogram",
+	}
+	for _, linea := range esperadas {
+		if !strings.Contains(cuerpo, linea) {
+			t.Fatalf("falta la línea %q en:\n%s", linea, cuerpo)
+		}
+	}
+}
@@ -0,0 +1,386 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"runtime/debug"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// nivelDeLog traduce el nivel configurado (DEBUG/INFO/WARN/ERROR, sin
+// distinguir mayúsculas) a slog.Level, cayendo a INFO si no se reconoce.
+func nivelDeLog(texto string) slog.Level {
+	switch strings.ToUpper(strings.TrimSpace(texto)) {
+	case "DEBUG":
+		return slog.LevelDebug
+	case "WARN":
+		return slog.LevelWarn
+	case "ERROR":
+		return slog.LevelError
+	default:
+		return slog.LevelInfo
+	}
+}
+
+// nuevoRegistrador construye el logger estructurado del servidor: JSON en
+// stderr con el nivel configurado y el nombre del servicio como atributo
+// fijo.
+func nuevoRegistrador(config *Configuracion) *slog.Logger {
+	return nuevoRegistradorEn(os.Stderr, config)
+}
+
+// nuevoRegistradorEn es la variante de nuevoRegistrador con el destino
+// inyectable; las pruebas la usan para capturar los logs en un buffer.
+func nuevoRegistradorEn(salida io.Writer, config *Configuracion) *slog.Logger {
+	manejador := slog.NewJSONHandler(salida, &slog.HandlerOptions{Level: nivelDeLog(config.NivelLog)})
+	return slog.New(manejador).With("servicio", config.NombreServicio)
+}
+
+// MiddlewareRegistro emite una línea JSON estructurada por petición con
+// método, ruta, código de estado, duración e ID de correlación. El inicio
+// de la petición solo se registra en nivel DEBUG.
+func (s *ServidorHTTP) MiddlewareRegistro(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		inicio := time.Now()
+		escritor := &escritorConEstado{ResponseWriter: w, codigo: http.StatusOK}
+
+		s.registrador.Debug("petición iniciada",
+			"metodo", r.Method,
+			"ruta", r.URL.Path,
+			"remoto", r.RemoteAddr,
+		)
+		siguiente.ServeHTTP(escritor, r)
+
+		atributos := []any{
+			"metodo", r.Method,
+			"ruta", r.URL.Path,
+			"codigo", escritor.codigo,
+			"duracion_ms", float64(time.Since(inicio).Microseconds()) / 1000,
+		}
+		if id := IDDeContexto(r.Context()); id != "" {
+			atributos = append(atributos, "id_correlacion", id)
+		}
+		s.registrador.Info("petición completada", atributos...)
+	})
+}
+
+// MiddlewareRecuperacion atrapa los pánicos de los handlers para que una
+// petición rota no tumbe el proceso ni deje la conexión colgada. El stack
+// trace va al log y el cliente recibe un 500 genérico sin detalles
+// internos.
+func (s *ServidorHTTP) MiddlewareRecuperacion(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer func() {
+			if valor := recover(); valor != nil {
+				s.registrador.Error("pánico recuperado",
+					"metodo", r.Method,
+					"ruta", r.URL.Path,
+					"panico", valor,
+					"stack", string(debug.Stack()),
+				)
+				enviarRespuestaJSON(w, http.StatusInternalServerError, RespuestaJSON{
+					Exitoso:     false,
+					Error:       "Error interno del servidor",
+					CodigoError: CodigoErrorInterno,
+				})
+			}
+		}()
+		siguiente.ServeHTTP(w, r)
+	})
+}
+
+// limiteCuerpoPorDefecto es el tamaño máximo de cuerpo cuando la
+// configuración no especifica otro.
+const limiteCuerpoPorDefecto = 1 << 20 // 1MB
+
+// MiddlewareLimiteCuerpo acota el cuerpo de todas las peticiones con
+// http.MaxBytesReader para que un cliente no pueda agotar memoria con un
+// cuerpo gigante. El exceso aflora como 413 en decodificarCuerpo.
+func (s *ServidorHTTP) MiddlewareLimiteCuerpo(siguiente http.Handler) http.Handler {
+	limite := s.configuracion.LimiteTamanoCuerpo
+	if limite <= 0 {
+		limite = limiteCuerpoPorDefecto
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Body != nil {
+			r.Body = http.MaxBytesReader(w, r.Body, limite)
+		}
+		siguiente.ServeHTTP(w, r)
+	})
+}
+
+// MiddlewareCORS añade las cabeceras CORS a todas las respuestas. Con
+// OrigenesPermitidos configurado se refleja el Origin de la petición solo
+// si está en la lista y se omite el header si no coincide; con la lista
+// vacía se mantiene el comodín "*".
+func (s *ServidorHTTP) MiddlewareCORS(siguiente http.Handler) http.Handler {
+	permitidos := make(map[string]bool, len(s.configuracion.OrigenesPermitidos))
+	for _, origen := range s.configuracion.OrigenesPermitidos {
+		permitidos[origen] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(permitidos) == 0 {
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+		} else if origen := r.Header.Get("Origin"); permitidos[origen] {
+			w.Header().Set("Access-Control-Allow-Origin", origen)
+			w.Header().Set("Vary", "Origin")
+			if s.configuracion.PermitirCredenciales {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
+
+		if r.Method == http.MethodOptions {
+			w.WriteHeader(http.StatusNoContent)
+			return
+		}
+
+		siguiente.ServeHTTP(w, r)
+	})
+}
+
+// hostSinPuerto normaliza el valor de la cabecera Host a minúsculas y
+// sin el puerto, que varía según cómo el proxy reenvíe la petición.
+func hostSinPuerto(host string) string {
+	if anfitrion, _, err := net.SplitHostPort(host); err == nil {
+		host = anfitrion
+	}
+	return strings.ToLower(strings.TrimSpace(host))
+}
+
+// MiddlewareHostPermitido rechaza con 400 las peticiones cuyo Host no
+// esté en HostsPermitidos, para cortar ataques de host header detrás de
+// proxies mal configurados: los enlaces HATEOAS y el Location de creación
+// se generan a partir de ese valor. Las entradas "*.dominio" admiten
+// cualquier subdominio (no el dominio pelado); con la lista vacía el
+// middleware no se monta y todo Host pasa, como siempre.
+func (s *ServidorHTTP) MiddlewareHostPermitido(siguiente http.Handler) http.Handler {
+	exactos := make(map[string]bool, len(s.configuracion.HostsPermitidos))
+	var comodines []string
+	for _, permitido := range s.configuracion.HostsPermitidos {
+		permitido = strings.ToLower(strings.TrimSpace(permitido))
+		if sufijo, esComodin := strings.CutPrefix(permitido, "*."); esComodin {
+			comodines = append(comodines, "."+sufijo)
+			continue
+		}
+		exactos[permitido] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := hostSinPuerto(r.Host)
+		admitido := exactos[host]
+		for indice := 0; !admitido && indice < len(comodines); indice++ {
+			admitido = strings.HasSuffix(host, comodines[indice])
+		}
+		if !admitido {
+			enviarRespuestaJSON(w, http.StatusBadRequest, RespuestaJSON{
+				Exitoso:     false,
+				Error:       fmt.Sprintf("El host %q no está permitido", r.Host),
+				CodigoError: CodigoHostNoPermitido,
+			})
+			return
+		}
+		siguiente.ServeHTTP(w, r)
+	})
+}
+
+// respuestaSoloCabeceras captura el código y mide el cuerpo que el
+// handler habría escrito, sin enviarlo; al finalizar emite las cabeceras
+// con el Content-Length calculado. Es el soporte de los HEAD.
+type respuestaSoloCabeceras struct {
+	destino http.ResponseWriter
+	codigo  int
+	fijado  bool
+	bytes   int64
+}
+
+func (r *respuestaSoloCabeceras) Header() http.Header {
+	return r.destino.Header()
+}
+
+func (r *respuestaSoloCabeceras) WriteHeader(codigo int) {
+	if !r.fijado {
+		r.codigo = codigo
+		r.fijado = true
+	}
+}
+
+func (r *respuestaSoloCabeceras) Write(p []byte) (int, error) {
+	r.fijado = true
+	r.bytes += int64(len(p))
+	return len(p), nil
+}
+
+func (r *respuestaSoloCabeceras) finalizar() {
+	r.destino.Header().Set("Content-Length", strconv.FormatInt(r.bytes, 10))
+	r.destino.WriteHeader(r.codigo)
+}
+
+// MiddlewareHEAD atiende los HEAD con los handlers GET existentes: la
+// petición se presenta al handler como GET y la respuesta se descarta
+// midiendo su tamaño, de modo que el cliente recibe los mismos headers
+// (incluido Content-Length) sin cuerpo. Debe envolver directamente al
+// mux para que los handlers no vean nunca el método HEAD.
+func MiddlewareHEAD(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			siguiente.ServeHTTP(w, r)
+			return
+		}
+		clon := r.Clone(r.Context())
+		clon.Method = http.MethodGet
+		respuesta := &respuestaSoloCabeceras{destino: w, codigo: http.StatusOK}
+		siguiente.ServeHTTP(respuesta, clon)
+		respuesta.finalizar()
+	})
+}
+
+// tiposDeCuerpoAdmitidos son los media types que algún handler sabe
+// decodificar; el resto se rechaza antes de llegar a los handlers.
+var tiposDeCuerpoAdmitidos = map[string]bool{
+	"application/json":             true,
+	"application/merge-patch+json": true, // PATCH RFC 7386
+	"text/csv":                     true, // POST /usuarios/importar
+}
+
+// MiddlewareContentType exige un Content-Type conocido en las peticiones
+// POST/PUT/PATCH que traen cuerpo y responde 415 en caso contrario, en
+// lugar del error críptico de decodificación que daría el handler. Los
+// parámetros como "; charset=utf-8" se toleran. Las peticiones sin cuerpo
+// (por ejemplo los POST administrativos) pasan sin chequeo.
+func MiddlewareContentType(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conCuerpo := r.Method == http.MethodPost || r.Method == http.MethodPut || r.Method == http.MethodPatch
+		if conCuerpo && r.ContentLength != 0 && !tiposDeCuerpoAdmitidos[tipoDeContenido(r)] {
+			enviarRespuestaJSON(w, http.StatusUnsupportedMediaType, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "Content-Type no soportado; use application/json",
+				CodigoError: CodigoFormatoNoSoportado,
+			})
+			return
+		}
+		siguiente.ServeHTTP(w, r)
+	})
+}
+
+// esMetodoEscritura indica si el método HTTP corresponde a una operación de
+// escritura o persistencia, que tiene un perfil de latencia más lento que
+// una lectura.
+func esMetodoEscritura(metodo string) bool {
+	switch metodo {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// MiddlewareTiempoEspera aplica un timeout por contexto según el tipo de
+// operación: TiempoLectura para los GET y TiempoEscritura para los métodos
+// de escritura. Si el handler no termina dentro del plazo se responde 504
+// y la respuesta parcial se descarta. Un timeout de cero desactiva el
+// límite para ese tipo de operación. Para poder descartar lo escrito, la
+// respuesta se retiene completa hasta que el handler termina: las
+// respuestas en streaming (como la exportación NDJSON) pierden su Flush
+// por línea mientras estos timeouts estén activos.
+func (s *ServidorHTTP) MiddlewareTiempoEspera(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limite := time.Duration(s.configuracion.TiempoLectura)
+		if esMetodoEscritura(r.Method) {
+			limite = time.Duration(s.configuracion.TiempoEscritura)
+		}
+		if limite <= 0 {
+			siguiente.ServeHTTP(w, r)
+			return
+		}
+
+		ctx, cancelar := context.WithTimeout(r.Context(), limite)
+		defer cancelar()
+
+		grabadora := nuevaRespuestaDiferida()
+		terminado := make(chan struct{})
+		go func() {
+			defer close(terminado)
+			// recover no cruza goroutines: el MiddlewareRecuperacion de
+			// la cadena vive en la goroutine padre y no vería un pánico
+			// de aquí dentro, que tumbaría el proceso entero. Se recupera
+			// en esta misma goroutine, descartando lo ya escrito para que
+			// el volcado entregue el mismo 500 limpio de siempre.
+			defer func() {
+				if valor := recover(); valor != nil {
+					s.registrador.Error("pánico recuperado",
+						"metodo", r.Method,
+						"ruta", r.URL.Path,
+						"panico", valor,
+						"stack", string(debug.Stack()),
+					)
+					grabadora.reiniciar()
+					enviarRespuestaJSON(grabadora, http.StatusInternalServerError, RespuestaJSON{
+						Exitoso:     false,
+						Error:       "Error interno del servidor",
+						CodigoError: CodigoErrorInterno,
+					})
+				}
+			}()
+			siguiente.ServeHTTP(grabadora, r.WithContext(ctx))
+		}()
+
+		select {
+		case <-terminado:
+			grabadora.volcarEn(w, s.registrador)
+		case <-ctx.Done():
+			enviarRespuestaJSON(w, http.StatusGatewayTimeout, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "La operación excedió el tiempo de espera",
+				CodigoError: CodigoTiempoEsperaAgotado,
+			})
+		}
+	})
+}
+
+// respuestaDiferida acumula la respuesta de un handler para poder
+// descartarla si el timeout vence antes de que termine.
+type respuestaDiferida struct {
+	cabeceras http.Header
+	codigo    int
+	cuerpo    []byte
+}
+
+func nuevaRespuestaDiferida() *respuestaDiferida {
+	return &respuestaDiferida{cabeceras: http.Header{}, codigo: http.StatusOK}
+}
+
+func (rd *respuestaDiferida) Header() http.Header { return rd.cabeceras }
+
+func (rd *respuestaDiferida) WriteHeader(codigo int) { rd.codigo = codigo }
+
+func (rd *respuestaDiferida) Write(datos []byte) (int, error) {
+	rd.cuerpo = append(rd.cuerpo, datos...)
+	return len(datos), nil
+}
+
+// reiniciar descarta lo acumulado para sustituirlo por otra respuesta.
+func (rd *respuestaDiferida) reiniciar() {
+	rd.cabeceras = http.Header{}
+	rd.codigo = http.StatusOK
+	rd.cuerpo = nil
+}
+
+// volcarEn copia la respuesta acumulada al ResponseWriter real.
+func (rd *respuestaDiferida) volcarEn(w http.ResponseWriter, registrador *slog.Logger) {
+	for clave, valores := range rd.cabeceras {
+		for _, valor := range valores {
+			w.Header().Add(clave, valor)
+		}
+	}
+	w.WriteHeader(rd.codigo)
+	if _, err := w.Write(rd.cuerpo); err != nil {
+		registrador.Error("error al escribir respuesta diferida", "error", err)
+	}
+}
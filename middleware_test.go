@@ -0,0 +1,411 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMiddlewareRegistroEmiteJSONEstructurado(t *testing.T) {
+	var bufer bytes.Buffer
+	servidor := NuevoServidor(&Configuracion{NombreServicio: "joker-pruebas"})
+	servidor.registrador = slog.New(slog.NewJSONHandler(&bufer, nil)).With("servicio", "joker-pruebas")
+
+	manejador := MiddlewareIDCorrelacion(servidor.MiddlewareRegistro(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})))
+	manejador.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/no-existe", nil))
+
+	var linea struct {
+		Mensaje       string  `json:"msg"`
+		Servicio      string  `json:"servicio"`
+		Metodo        string  `json:"metodo"`
+		Ruta          string  `json:"ruta"`
+		Codigo        int     `json:"codigo"`
+		DuracionMS    float64 `json:"duracion_ms"`
+		IDCorrelacion string  `json:"id_correlacion"`
+	}
+	if err := json.Unmarshal(bufer.Bytes(), &linea); err != nil {
+		t.Fatalf("la salida no es JSON válido: %v\n%s", err, bufer.String())
+	}
+	if linea.Servicio != "joker-pruebas" || linea.Metodo != http.MethodGet || linea.Ruta != "/no-existe" {
+		t.Fatalf("campos estructurados inesperados: %+v", linea)
+	}
+	if linea.Codigo != http.StatusNotFound {
+		t.Fatalf("el código capturado debería ser 404, es %d", linea.Codigo)
+	}
+	if linea.IDCorrelacion == "" {
+		t.Fatal("falta el ID de correlación en la línea de log")
+	}
+}
+
+func TestEstablecerSalidaLogsCapturaEnBuffer(t *testing.T) {
+	var bufer bytes.Buffer
+	servidor := NuevoServidor(&Configuracion{NombreServicio: "joker-pruebas"})
+	servidor.EstablecerSalidaLogs(&bufer)
+
+	servidor.registrador.Info("mensaje de prueba", "clave", "valor")
+
+	var linea struct {
+		Mensaje  string `json:"msg"`
+		Servicio string `json:"servicio"`
+		Clave    string `json:"clave"`
+	}
+	if err := json.Unmarshal(bufer.Bytes(), &linea); err != nil {
+		t.Fatalf("la salida capturada no es JSON válido: %v\n%s", err, bufer.String())
+	}
+	if linea.Mensaje != "mensaje de prueba" || linea.Servicio != "joker-pruebas" || linea.Clave != "valor" {
+		t.Fatalf("línea capturada inesperada: %+v", linea)
+	}
+}
+
+func TestNivelDeLogConfigurable(t *testing.T) {
+	var bufer bytes.Buffer
+	servidor := NuevoServidor(&Configuracion{NivelLog: "ERROR"})
+	servidor.registrador = slog.New(slog.NewJSONHandler(&bufer, &slog.HandlerOptions{Level: nivelDeLog("ERROR")}))
+
+	manejador := servidor.MiddlewareRegistro(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	manejador.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/salud", nil))
+
+	if bufer.Len() != 0 {
+		t.Fatalf("en nivel ERROR no deberían emitirse líneas INFO: %s", bufer.String())
+	}
+	if nivelDeLog("desconocido") != slog.LevelInfo {
+		t.Fatal("un nivel irreconocible debería caer a INFO")
+	}
+}
+
+func TestMiddlewareCORSConOrigenesConfigurados(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{
+		OrigenesPermitidos:   []string{"https://app.ejemplo.com"},
+		PermitirCredenciales: true,
+	})
+	manejador := servidor.MiddlewareCORS(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	pedir := func(origen string) *httptest.ResponseRecorder {
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+		if origen != "" {
+			peticion.Header.Set("Origin", origen)
+		}
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora
+	}
+
+	t.Run("origen permitido se refleja con credenciales", func(t *testing.T) {
+		grabadora := pedir("https://app.ejemplo.com")
+		if valor := grabadora.Header().Get("Access-Control-Allow-Origin"); valor != "https://app.ejemplo.com" {
+			t.Fatalf("Allow-Origin esperado reflejado, obtenido %q", valor)
+		}
+		if grabadora.Header().Get("Access-Control-Allow-Credentials") != "true" {
+			t.Fatal("falta Access-Control-Allow-Credentials para el origen permitido")
+		}
+	})
+
+	t.Run("origen no permitido queda sin header", func(t *testing.T) {
+		grabadora := pedir("https://atacante.ejemplo.com")
+		if valor := grabadora.Header().Get("Access-Control-Allow-Origin"); valor != "" {
+			t.Fatalf("un origen no permitido no debería recibir Allow-Origin, recibió %q", valor)
+		}
+	})
+
+	t.Run("lista vacía mantiene el comodín", func(t *testing.T) {
+		abierto := NuevoServidor(&Configuracion{})
+		grabadora := httptest.NewRecorder()
+		abierto.MiddlewareCORS(manejador).ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+		if valor := grabadora.Header().Get("Access-Control-Allow-Origin"); valor != "*" {
+			t.Fatalf("sin lista configurada se esperaba el comodín, obtenido %q", valor)
+		}
+	})
+}
+
+func TestMiddlewareHostPermitido(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{
+		HostsPermitidos: []string{"api.ejemplo.com", "*.interno.ejemplo.com"},
+	})
+	manejador := servidor.MiddlewareHostPermitido(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	pedir := func(host string) *httptest.ResponseRecorder {
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodGet, "/usuarios", nil)
+		peticion.Host = host
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora
+	}
+
+	t.Run("host permitido pasa, con o sin puerto", func(t *testing.T) {
+		for _, host := range []string{"api.ejemplo.com", "API.ejemplo.com:8443"} {
+			if grabadora := pedir(host); grabadora.Code != http.StatusOK {
+				t.Fatalf("el host %q debería aceptarse, dio %d", host, grabadora.Code)
+			}
+		}
+	})
+
+	t.Run("comodín admite cualquier subdominio", func(t *testing.T) {
+		if grabadora := pedir("staging.interno.ejemplo.com"); grabadora.Code != http.StatusOK {
+			t.Fatalf("el subdominio debería aceptarse, dio %d", grabadora.Code)
+		}
+		// El comodín no cubre el dominio pelado.
+		if grabadora := pedir("interno.ejemplo.com"); grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("el dominio pelado no debería cubrirlo el comodín, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("host desconocido recibe 400 con código estable", func(t *testing.T) {
+		grabadora := pedir("atacante.com")
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un host fuera de la lista debería dar 400, dio %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), CodigoHostNoPermitido) {
+			t.Fatalf("falta el código de error: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("sin lista configurada todo host pasa", func(t *testing.T) {
+		abierto := NuevoServidor(&Configuracion{})
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodGet, "/salud", nil)
+		peticion.Host = "cualquiera.com"
+		abierto.Manejador().ServeHTTP(grabadora, peticion)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("sin lista, cualquier host debería pasar, dio %d", grabadora.Code)
+		}
+	})
+}
+
+func TestMiddlewareRecuperacionDevuelve500Limpio(t *testing.T) {
+	var bufer bytes.Buffer
+	servidor := NuevoServidor(&Configuracion{})
+	servidor.registrador = slog.New(slog.NewJSONHandler(&bufer, nil))
+
+	manejador := servidor.MiddlewareRecuperacion(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		panic("algo salió muy mal")
+	}))
+
+	grabadora := httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+
+	if grabadora.Code != http.StatusInternalServerError {
+		t.Fatalf("un pánico debería responder 500, respondió %d", grabadora.Code)
+	}
+	if strings.Contains(grabadora.Body.String(), "algo salió muy mal") {
+		t.Fatalf("la respuesta filtra el detalle del pánico: %s", grabadora.Body.String())
+	}
+	if !strings.Contains(grabadora.Body.String(), "Error interno del servidor") {
+		t.Fatalf("cuerpo inesperado: %s", grabadora.Body.String())
+	}
+	if !strings.Contains(bufer.String(), "algo salió muy mal") || !strings.Contains(bufer.String(), "stack") {
+		t.Fatalf("el log debería incluir el pánico y el stack: %s", bufer.String())
+	}
+}
+
+func TestPanicoDentroDelTiempoEsperaDevuelve500(t *testing.T) {
+	var bufer bytes.Buffer
+	servidor := NuevoServidor(&Configuracion{TiempoLectura: Duracion(time.Second)})
+	servidor.registrador = slog.New(slog.NewJSONHandler(&bufer, nil))
+
+	// El handler corre en la goroutine propia del timeout, donde el
+	// recover de MiddlewareRecuperacion (goroutine padre) no llega: el
+	// pánico debe recuperarse dentro y descartar lo ya escrito.
+	manejador := servidor.MiddlewareRecuperacion(servidor.MiddlewareTiempoEspera(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte("respuesta parcial"))
+		panic("algo salió muy mal")
+	})))
+
+	grabadora := httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+
+	if grabadora.Code != http.StatusInternalServerError {
+		t.Fatalf("un pánico con timeout activo debería responder 500, respondió %d", grabadora.Code)
+	}
+	if strings.Contains(grabadora.Body.String(), "respuesta parcial") {
+		t.Fatalf("la respuesta no debería arrastrar lo escrito antes del pánico: %s", grabadora.Body.String())
+	}
+	if !strings.Contains(grabadora.Body.String(), CodigoErrorInterno) {
+		t.Fatalf("cuerpo inesperado: %s", grabadora.Body.String())
+	}
+	if !strings.Contains(bufer.String(), "algo salió muy mal") {
+		t.Fatalf("el log debería incluir el pánico: %s", bufer.String())
+	}
+}
+
+func TestMiddlewareTiempoEsperaPorOperacion(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{
+		TiempoLectura:   Duracion(30 * time.Millisecond),
+		TiempoEscritura: Duracion(500 * time.Millisecond),
+	})
+
+	// Handler que tarda más que el timeout de lectura pero menos que el
+	// de escritura, sin importar el método.
+	lento := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case <-time.After(100 * time.Millisecond):
+			enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{Exitoso: true})
+		case <-r.Context().Done():
+		}
+	})
+	manejador := servidor.MiddlewareTiempoEspera(lento)
+
+	t.Run("GET lento excede el timeout de lectura", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+		if grabadora.Code != http.StatusGatewayTimeout {
+			t.Fatalf("código esperado 504, obtenido %d", grabadora.Code)
+		}
+	})
+
+	t.Run("POST lento cabe en el timeout de escritura", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+	})
+
+	t.Run("timeout cero no limita", func(t *testing.T) {
+		sinLimites := NuevoServidor(&Configuracion{})
+		grabadora := httptest.NewRecorder()
+		sinLimites.MiddlewareTiempoEspera(lento).ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+	})
+}
+
+func TestMiddlewareLimiteCuerpoResponde413(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{LimiteTamanoCuerpo: 64})
+	manejador := servidor.MiddlewareLimiteCuerpo(http.HandlerFunc(servidor.manejarUsuarios))
+
+	gigante := `{"nombre":"` + strings.Repeat("a", 200) + `","email":"a@ejemplo.com"}`
+	grabadora := httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", strings.NewReader(gigante)))
+	if grabadora.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("un cuerpo sobre el límite debería dar 413, dio %d", grabadora.Code)
+	}
+
+	pequeno := `{"nombre":"Ana","email":"ana@ejemplo.com"}`
+	grabadora = httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", strings.NewReader(pequeno)))
+	if grabadora.Code != http.StatusCreated {
+		t.Fatalf("un cuerpo dentro del límite debería pasar, dio %d", grabadora.Code)
+	}
+}
+
+func TestMiddlewareContentTypeExigeJSON(t *testing.T) {
+	manejador := MiddlewareContentType(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	enviar := func(tipo string) *httptest.ResponseRecorder {
+		peticion := httptest.NewRequest(http.MethodPost, "/usuarios", strings.NewReader(`{"nombre":"Ana"}`))
+		if tipo != "" {
+			peticion.Header.Set("Content-Type", tipo)
+		}
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora
+	}
+
+	t.Run("application/json pasa", func(t *testing.T) {
+		if codigo := enviar("application/json").Code; codigo != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", codigo)
+		}
+	})
+
+	t.Run("los parámetros del media type se toleran", func(t *testing.T) {
+		if codigo := enviar("application/json; charset=utf-8").Code; codigo != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", codigo)
+		}
+	})
+
+	t.Run("un tipo incorrecto da 415", func(t *testing.T) {
+		grabadora := enviar("text/plain")
+		if grabadora.Code != http.StatusUnsupportedMediaType {
+			t.Fatalf("código esperado 415, obtenido %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), CodigoFormatoNoSoportado) {
+			t.Fatalf("falta el código de error: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("sin Content-Type con cuerpo da 415", func(t *testing.T) {
+		if codigo := enviar("").Code; codigo != http.StatusUnsupportedMediaType {
+			t.Fatalf("código esperado 415, obtenido %d", codigo)
+		}
+	})
+
+	t.Run("un POST sin cuerpo pasa sin chequeo", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodPost, "/admin/drenar", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+	})
+
+	t.Run("los GET no se tocan", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+	})
+}
+
+func TestHEADRespondeSinCuerpo(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	manejador := servidor.construirManejador()
+
+	t.Run("HEAD /salud trae headers y cuerpo vacío", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodHead, "/salud", nil))
+
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+		if grabadora.Body.Len() != 0 {
+			t.Fatalf("un HEAD no debe llevar cuerpo: %q", grabadora.Body.String())
+		}
+		if tipo := grabadora.Header().Get("Content-Type"); !strings.HasPrefix(tipo, "application/json") {
+			t.Fatalf("faltan los headers del GET equivalente, Content-Type fue %q", tipo)
+		}
+		if longitud := grabadora.Header().Get("Content-Length"); longitud == "" || longitud == "0" {
+			t.Fatalf("Content-Length debería reflejar el cuerpo del GET, fue %q", longitud)
+		}
+	})
+
+	t.Run("HEAD a un usuario existente da 200 con ETag", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodHead, "/api/v1/usuarios/1", nil))
+
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+		if grabadora.Body.Len() != 0 {
+			t.Fatalf("un HEAD no debe llevar cuerpo: %q", grabadora.Body.String())
+		}
+		if grabadora.Header().Get("ETag") == "" {
+			t.Fatal("el HEAD debería traer el mismo ETag que el GET")
+		}
+	})
+
+	t.Run("HEAD a un usuario inexistente da 404", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		manejador.ServeHTTP(grabadora, httptest.NewRequest(http.MethodHead, "/api/v1/usuarios/999", nil))
+		if grabadora.Code != http.StatusNotFound {
+			t.Fatalf("código esperado 404, obtenido %d", grabadora.Code)
+		}
+		if grabadora.Body.Len() != 0 {
+			t.Fatalf("tampoco el 404 de un HEAD lleva cuerpo: %q", grabadora.Body.String())
+		}
+	})
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// formatoAceptado interpreta el header Accept y devuelve "json", "xml",
+// "ndjson" o cadena vacía si ningún tipo listado es soportado. Sin header
+// se asume JSON.
+func formatoAceptado(accept string) string {
+	if strings.TrimSpace(accept) == "" {
+		return "json"
+	}
+	for _, parte := range strings.Split(accept, ",") {
+		tipo, _, _ := strings.Cut(strings.TrimSpace(parte), ";")
+		switch strings.ToLower(tipo) {
+		case "application/json", "*/*", "application/*":
+			return "json"
+		case "application/xml", "text/xml":
+			return "xml"
+		case "application/x-ndjson":
+			return "ndjson"
+		}
+	}
+	return ""
+}
+
+// escribirElementoXML serializa recursivamente un valor decodificado de
+// JSON como elementos XML. encoding/xml no sabe serializar mapas, que es
+// justo lo que llevan los Datos de la envoltura, así que la conversión se
+// hace a mano: claves ordenadas para salida determinista y los elementos
+// de listas bajo <elemento>.
+func escribirElementoXML(w io.Writer, etiqueta string, valor interface{}) {
+	switch v := valor.(type) {
+	case map[string]interface{}:
+		fmt.Fprintf(w, "<%s>", etiqueta)
+		claves := make([]string, 0, len(v))
+		for clave := range v {
+			claves = append(claves, clave)
+		}
+		sort.Strings(claves)
+		for _, clave := range claves {
+			escribirElementoXML(w, clave, v[clave])
+		}
+		fmt.Fprintf(w, "</%s>", etiqueta)
+	case []interface{}:
+		fmt.Fprintf(w, "<%s>", etiqueta)
+		for _, elemento := range v {
+			escribirElementoXML(w, "elemento", elemento)
+		}
+		fmt.Fprintf(w, "</%s>", etiqueta)
+	case nil:
+		fmt.Fprintf(w, "<%s/>", etiqueta)
+	default:
+		fmt.Fprintf(w, "<%s>", etiqueta)
+		xml.EscapeText(w, []byte(fmt.Sprint(v)))
+		fmt.Fprintf(w, "</%s>", etiqueta)
+	}
+}
+
+// escritorNegociado retiene la respuesta JSON de un handler para poder
+// reexpresarla en XML al terminar.
+type escritorNegociado struct {
+	http.ResponseWriter
+	codigo int
+	cuerpo bytes.Buffer
+}
+
+func (e *escritorNegociado) WriteHeader(codigo int) { e.codigo = codigo }
+
+func (e *escritorNegociado) Write(datos []byte) (int, error) {
+	return e.cuerpo.Write(datos)
+}
+
+// MiddlewareNegociacion atiende el header Accept: los clientes que piden
+// application/xml reciben la misma envoltura serializada como XML, los
+// que piden JSON (o nada, o */*) siguen igual, y un Accept sin ningún
+// formato soportado responde 406.
+func MiddlewareNegociacion(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch formatoAceptado(r.Header.Get("Accept")) {
+		case "json", "ndjson":
+			// El NDJSON lo produce directamente el handler que lo soporta;
+			// aquí solo se deja pasar sin reexpresar nada.
+			siguiente.ServeHTTP(w, r)
+			return
+		case "":
+			enviarRespuestaJSON(w, http.StatusNotAcceptable, RespuestaJSON{
+				Exitoso:     false,
+				Error:       "Formato no soportado; los disponibles son application/json y application/xml",
+				CodigoError: CodigoFormatoNoSoportado,
+			})
+			return
+		}
+
+		escritor := &escritorNegociado{ResponseWriter: w, codigo: http.StatusOK}
+		siguiente.ServeHTTP(escritor, r)
+
+		var generico interface{}
+		if err := json.Unmarshal(escritor.cuerpo.Bytes(), &generico); err != nil {
+			// El handler no emitió JSON (métricas, exportaciones); se
+			// entrega tal cual respetando sus cabeceras.
+			w.WriteHeader(escritor.codigo)
+			w.Write(escritor.cuerpo.Bytes())
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		w.WriteHeader(escritor.codigo)
+		io.WriteString(w, xml.Header)
+		escribirElementoXML(w, "respuesta", generico)
+	})
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddlewareNegociacionDeContenido(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	manejador := MiddlewareNegociacion(http.HandlerFunc(servidor.manejarSalud))
+
+	pedir := func(accept string) *httptest.ResponseRecorder {
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodGet, "/salud", nil)
+		if accept != "" {
+			peticion.Header.Set("Accept", accept)
+		}
+		manejador.ServeHTTP(grabadora, peticion)
+		return grabadora
+	}
+
+	t.Run("JSON por defecto y bajo demanda", func(t *testing.T) {
+		for _, accept := range []string{"", "application/json", "*/*"} {
+			grabadora := pedir(accept)
+			if tipo := grabadora.Header().Get("Content-Type"); !strings.Contains(tipo, "application/json") {
+				t.Fatalf("con Accept %q se esperaba JSON, Content-Type %q", accept, tipo)
+			}
+			if !strings.Contains(grabadora.Body.String(), `"exitoso":true`) {
+				t.Fatalf("cuerpo JSON inesperado: %s", grabadora.Body.String())
+			}
+		}
+	})
+
+	t.Run("XML cuando el cliente lo pide", func(t *testing.T) {
+		grabadora := pedir("application/xml")
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+		if tipo := grabadora.Header().Get("Content-Type"); !strings.Contains(tipo, "application/xml") {
+			t.Fatalf("Content-Type esperado XML, obtenido %q", tipo)
+		}
+		cuerpo := grabadora.Body.String()
+		if !strings.Contains(cuerpo, "<respuesta>") || !strings.Contains(cuerpo, "<exitoso>true</exitoso>") || !strings.Contains(cuerpo, "<mensaje>saludable</mensaje>") {
+			t.Fatalf("XML inesperado: %s", cuerpo)
+		}
+	})
+
+	t.Run("formato no soportado da 406", func(t *testing.T) {
+		grabadora := pedir("text/csv")
+		if grabadora.Code != http.StatusNotAcceptable {
+			t.Fatalf("un formato no soportado debería dar 406, dio %d", grabadora.Code)
+		}
+	})
+}
+
+func TestFormatoAceptadoConListaYParametros(t *testing.T) {
+	if formato := formatoAceptado("text/html;q=0.9, application/xml;q=0.8"); formato != "xml" {
+		t.Fatalf("debería elegirse XML de la lista, eligió %q", formato)
+	}
+	if formato := formatoAceptado("text/html, */*;q=0.1"); formato != "json" {
+		t.Fatalf("*/* debería caer a JSON, eligió %q", formato)
+	}
+}
@@ -0,0 +1,301 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+)
+
+// Tipos mínimos del documento OpenAPI 3.0. Solo modelan lo que este
+// servicio necesita describir; mantener la especificación como structs
+// evita un archivo YAML aparte que se desincronice del código.
+
+type documentoOpenAPI struct {
+	OpenAPI    string                                 `json:"openapi"`
+	Info       infoOpenAPI                            `json:"info"`
+	Paths      map[string]map[string]operacionOpenAPI `json:"paths"`
+	Components componentesOpenAPI                     `json:"components"`
+}
+
+type infoOpenAPI struct {
+	Titulo      string `json:"title"`
+	Descripcion string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+type operacionOpenAPI struct {
+	Resumen    string                      `json:"summary"`
+	Parametros []parametroOpenAPI          `json:"parameters,omitempty"`
+	Cuerpo     *cuerpoOpenAPI              `json:"requestBody,omitempty"`
+	Respuestas map[string]respuestaOpenAPI `json:"responses"`
+}
+
+type parametroOpenAPI struct {
+	Nombre      string         `json:"name"`
+	En          string         `json:"in"`
+	Requerido   bool           `json:"required,omitempty"`
+	Descripcion string         `json:"description,omitempty"`
+	Esquema     esquemaOpenAPI `json:"schema"`
+}
+
+type cuerpoOpenAPI struct {
+	Requerido bool                    `json:"required"`
+	Contenido map[string]medioOpenAPI `json:"content"`
+}
+
+type respuestaOpenAPI struct {
+	Descripcion string                  `json:"description"`
+	Contenido   map[string]medioOpenAPI `json:"content,omitempty"`
+}
+
+type medioOpenAPI struct {
+	Esquema esquemaOpenAPI `json:"schema"`
+}
+
+type esquemaOpenAPI struct {
+	Ref         string                    `json:"$ref,omitempty"`
+	Tipo        string                    `json:"type,omitempty"`
+	Formato     string                    `json:"format,omitempty"`
+	Propiedades map[string]esquemaOpenAPI `json:"properties,omitempty"`
+	Items       *esquemaOpenAPI           `json:"items,omitempty"`
+	Requeridos  []string                  `json:"required,omitempty"`
+}
+
+type componentesOpenAPI struct {
+	Esquemas map[string]esquemaOpenAPI `json:"schemas"`
+}
+
+// refEsquema abrevia las referencias a componentes.
+func refEsquema(nombre string) esquemaOpenAPI {
+	return esquemaOpenAPI{Ref: "#/components/schemas/" + nombre}
+}
+
+// respuestaEnvuelta es la respuesta estándar del API: RespuestaJSON.
+func respuestaEnvuelta(descripcion string) respuestaOpenAPI {
+	return respuestaOpenAPI{
+		Descripcion: descripcion,
+		Contenido: map[string]medioOpenAPI{
+			"application/json": {Esquema: refEsquema("RespuestaJSON")},
+		},
+	}
+}
+
+// construirOpenAPI arma el documento completo con las rutas de usuarios
+// (bajo el prefijo de versionado efectivo), salud y estado.
+func (s *ServidorHTTP) construirOpenAPI() documentoOpenAPI {
+	prefijo := s.prefijoAPI()
+	parametroID := parametroOpenAPI{
+		Nombre:      "id",
+		En:          "path",
+		Requerido:   true,
+		Descripcion: "ID numérico del usuario",
+		Esquema:     esquemaOpenAPI{Tipo: "integer"},
+	}
+	cuerpoUsuario := &cuerpoOpenAPI{
+		Requerido: true,
+		Contenido: map[string]medioOpenAPI{
+			"application/json": {Esquema: refEsquema("Usuario")},
+		},
+	}
+
+	return documentoOpenAPI{
+		OpenAPI: "3.0.3",
+		Info: infoOpenAPI{
+			Titulo:      "the-joker",
+			Descripcion: "API de gestión de usuarios con salud, estado y métricas.",
+			Version:     "1.0.0",
+		},
+		Paths: map[string]map[string]operacionOpenAPI{
+			prefijo + "/usuarios": {
+				"get": {
+					Resumen: "Lista usuarios con paginación, búsqueda y filtros de fecha",
+					Parametros: []parametroOpenAPI{
+						{Nombre: "pagina", En: "query", Esquema: esquemaOpenAPI{Tipo: "integer"}},
+						{Nombre: "tamano", En: "query", Esquema: esquemaOpenAPI{Tipo: "integer"}},
+						{Nombre: "buscar", En: "query", Esquema: esquemaOpenAPI{Tipo: "string"}},
+						{Nombre: "desde", En: "query", Descripcion: "Fecha de creación mínima (YYYY-MM-DD)", Esquema: esquemaOpenAPI{Tipo: "string", Formato: "date"}},
+						{Nombre: "hasta", En: "query", Descripcion: "Fecha de creación máxima (YYYY-MM-DD)", Esquema: esquemaOpenAPI{Tipo: "string", Formato: "date"}},
+					},
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("Lista paginada de usuarios"),
+						"400": respuestaEnvuelta("Parámetro inválido"),
+					},
+				},
+				"post": {
+					Resumen: "Crea un usuario",
+					Cuerpo:  cuerpoUsuario,
+					Respuestas: map[string]respuestaOpenAPI{
+						"201": respuestaEnvuelta("Usuario creado"),
+						"400": respuestaEnvuelta("Cuerpo o campos inválidos"),
+						"409": respuestaEnvuelta("Email o nombre duplicado"),
+						"422": respuestaEnvuelta("Email rechazado (desechable o sin MX)"),
+					},
+				},
+			},
+			prefijo + "/usuarios/lote": {
+				"post": {
+					Resumen: "Crea varios usuarios en una sola petición",
+					Cuerpo: &cuerpoOpenAPI{
+						Requerido: true,
+						Contenido: map[string]medioOpenAPI{
+							"application/json": {Esquema: esquemaOpenAPI{Tipo: "array", Items: &esquemaOpenAPI{Ref: "#/components/schemas/Usuario"}}},
+						},
+					},
+					Respuestas: map[string]respuestaOpenAPI{
+						"201": respuestaEnvuelta("Todos los usuarios creados"),
+						"207": respuestaEnvuelta("Resultado mixto, con detalle por elemento"),
+						"400": respuestaEnvuelta("Lote vacío o cuerpo inválido"),
+					},
+				},
+			},
+			prefijo + "/usuarios/importar": {
+				"post": {
+					Resumen: "Importa usuarios desde un CSV con columnas nombre,email",
+					Cuerpo: &cuerpoOpenAPI{
+						Requerido: true,
+						Contenido: map[string]medioOpenAPI{
+							"text/csv": {Esquema: esquemaOpenAPI{Tipo: "string"}},
+						},
+					},
+					Respuestas: map[string]respuestaOpenAPI{
+						"201": respuestaEnvuelta("Todas las filas importadas"),
+						"207": respuestaEnvuelta("Resultado mixto, con detalle por línea"),
+						"400": respuestaEnvuelta("CSV vacío, malformado o sin las columnas requeridas"),
+						"415": respuestaEnvuelta("Content-Type distinto de text/csv"),
+					},
+				},
+			},
+			prefijo + "/usuarios/exportar": {
+				"get": {
+					Resumen: "Exporta todos los usuarios como descarga CSV",
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": {
+							Descripcion: "Archivo CSV con un usuario por fila",
+							Contenido: map[string]medioOpenAPI{
+								"text/csv": {Esquema: esquemaOpenAPI{Tipo: "string"}},
+							},
+						},
+					},
+				},
+			},
+			prefijo + "/usuarios/contar": {
+				"get": {
+					Resumen: "Cuenta usuarios aplicando los mismos filtros que la lista",
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("Total de usuarios que pasan los filtros"),
+					},
+				},
+			},
+			prefijo + "/usuarios/{id}": {
+				"get": {
+					Resumen:    "Devuelve un usuario por ID, con caché condicional por ETag",
+					Parametros: []parametroOpenAPI{parametroID},
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("Usuario encontrado"),
+						"304": {Descripcion: "Sin cambios desde el ETag enviado"},
+						"404": respuestaEnvuelta("Usuario inexistente"),
+					},
+				},
+				"put": {
+					Resumen:    "Reemplaza el nombre y el email de un usuario",
+					Parametros: []parametroOpenAPI{parametroID},
+					Cuerpo:     cuerpoUsuario,
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("Usuario actualizado"),
+						"400": respuestaEnvuelta("Cuerpo o campos inválidos"),
+						"404": respuestaEnvuelta("Usuario inexistente"),
+					},
+				},
+				"patch": {
+					Resumen:    "Modifica solo los campos presentes en el cuerpo",
+					Parametros: []parametroOpenAPI{parametroID},
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("Usuario actualizado"),
+						"400": respuestaEnvuelta("Patch vacío o campos inválidos"),
+						"404": respuestaEnvuelta("Usuario inexistente"),
+					},
+				},
+				"delete": {
+					Resumen:    "Elimina un usuario",
+					Parametros: []parametroOpenAPI{parametroID},
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("Usuario eliminado"),
+						"404": respuestaEnvuelta("Usuario inexistente"),
+					},
+				},
+			},
+			"/salud": {
+				"get": {
+					Resumen: "Liveness básico del proceso",
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("El proceso responde"),
+					},
+				},
+			},
+			"/salud/detallado": {
+				"get": {
+					Resumen: "Salud con el detalle de cada verificación registrada",
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("Todas las verificaciones pasan"),
+						"503": respuestaEnvuelta("Alguna verificación falla"),
+					},
+				},
+			},
+			"/estado": {
+				"get": {
+					Resumen: "Información operativa: uptime, conteos y memoria",
+					Respuestas: map[string]respuestaOpenAPI{
+						"200": respuestaEnvuelta("Estado de la instancia"),
+					},
+				},
+			},
+		},
+		Components: componentesOpenAPI{
+			Esquemas: map[string]esquemaOpenAPI{
+				"Usuario": {
+					Tipo: "object",
+					Propiedades: map[string]esquemaOpenAPI{
+						"id":                {Tipo: "integer"},
+						"nombre":            {Tipo: "string"},
+						"email":             {Tipo: "string", Formato: "email"},
+						"fecha_creado":      {Tipo: "string"},
+						"metadatos":         {Tipo: "object"},
+						"fecha_actualizado": {Tipo: "string"},
+					},
+					Requeridos: []string{"nombre", "email"},
+				},
+				"RespuestaJSON": {
+					Tipo: "object",
+					Propiedades: map[string]esquemaOpenAPI{
+						"exitoso":      {Tipo: "boolean"},
+						"mensaje":      {Tipo: "string"},
+						"datos":        {},
+						"error":        {Tipo: "string"},
+						"codigo_error": {Tipo: "string"},
+					},
+					Requeridos: []string{"exitoso"},
+				},
+			},
+		},
+	}
+}
+
+// manejarOpenAPI sirve la especificación OpenAPI del servicio, generada
+// desde el propio código para que no haya un contrato aparte que
+// mantener.
+func (s *ServidorHTTP) manejarOpenAPI(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		enviarRespuestaJSON(w, http.StatusMethodNotAllowed, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "Método no permitido",
+			CodigoError: CodigoMetodoNoPermitido,
+		})
+		return
+	}
+	// El documento va sin la envoltura RespuestaJSON: los generadores de
+	// clientes esperan el objeto OpenAPI en la raíz.
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if err := json.NewEncoder(w).Encode(s.construirOpenAPI()); err != nil {
+		s.registrador.Error("error al serializar la especificación OpenAPI", "error", err)
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestOpenAPIServidoEnJSONValido(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarOpenAPI(grabadora, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+	}
+
+	var documento struct {
+		OpenAPI    string                            `json:"openapi"`
+		Paths      map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas map[string]interface{} `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &documento); err != nil {
+		t.Fatalf("el documento servido no es JSON válido: %v", err)
+	}
+	if documento.OpenAPI == "" {
+		t.Fatal("falta el campo openapi con la versión de la especificación")
+	}
+
+	usuarios, existe := documento.Paths["/api/v1/usuarios"]
+	if !existe {
+		t.Fatalf("la especificación debería documentar /api/v1/usuarios: %v", documento.Paths)
+	}
+	if _, existe := usuarios["post"]; !existe {
+		t.Fatal("falta la operación POST de /usuarios")
+	}
+	for _, esquema := range []string{"Usuario", "RespuestaJSON"} {
+		if _, existe := documento.Components.Schemas[esquema]; !existe {
+			t.Fatalf("falta el esquema %s en components", esquema)
+		}
+	}
+}
+
+func TestOpenAPIRespetaPrefijoConfigurado(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{PrefijoAPI: "/api/v2"})
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarOpenAPI(grabadora, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+
+	var documento struct {
+		Paths map[string]interface{} `json:"paths"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &documento); err != nil {
+		t.Fatal(err)
+	}
+	if _, existe := documento.Paths["/api/v2/usuarios"]; !existe {
+		t.Fatalf("las rutas documentadas deberían seguir el prefijo configurado: %v", documento.Paths)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+// cargarDesdeArchivo repone la lista de usuarios desde un archivo JSON;
+// el almacén reconstruye el contador de IDs al reemplazar. Un archivo
+// inexistente no es un error: se arranca con lista vacía.
+func (s *ServidorHTTP) cargarDesdeArchivo(ruta string) error {
+	datos, err := os.ReadFile(ruta)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return fmt.Errorf("error al leer %s: %w", ruta, err)
+	}
+
+	var usuarios []Usuario
+	if err := json.Unmarshal(datos, &usuarios); err != nil {
+		return fmt.Errorf("error al decodificar %s: %w", ruta, err)
+	}
+
+	if err := s.almacen.Reemplazar(usuarios); err != nil {
+		return fmt.Errorf("error al reponer el almacén: %w", err)
+	}
+	return nil
+}
+
+// guardarEnArchivo vuelca la lista de usuarios al archivo JSON dado,
+// escribiendo primero en un temporal para no corromper el archivo si el
+// proceso muere a mitad.
+func (s *ServidorHTTP) guardarEnArchivo(ruta string) error {
+	usuarios, err := s.almacen.ObtenerTodos()
+	if err != nil {
+		return fmt.Errorf("error al leer el almacén: %w", err)
+	}
+	datos, err := json.MarshalIndent(usuarios, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error al serializar usuarios: %w", err)
+	}
+
+	temporal := ruta + ".tmp"
+	if err := os.WriteFile(temporal, datos, 0o644); err != nil {
+		return fmt.Errorf("error al escribir %s: %w", temporal, err)
+	}
+	return os.Rename(temporal, ruta)
+}
+
+// verificarArchivoUsuarios comprueba que el directorio del archivo de
+// persistencia acepta escrituras, creando y borrando un archivo sonda. Es
+// la dependencia que reporta /listo cuando la persistencia está activa.
+func (s *ServidorHTTP) verificarArchivoUsuarios() error {
+	ruta := s.configuracion.RutaArchivoUsuarios
+	if ruta == "" {
+		return nil
+	}
+	sonda := ruta + ".sonda"
+	if err := os.WriteFile(sonda, []byte("ok"), 0o644); err != nil {
+		return fmt.Errorf("el archivo de usuarios no es escribible: %w", err)
+	}
+	return os.Remove(sonda)
+}
+
+// persistirUsuarios guarda tras cada operación de escritura cuando hay una
+// ruta configurada. Los errores se loggean pero no interrumpen la
+// respuesta al cliente. Con IntervaloSnapshot configurado solo se marca
+// el cambio; el volcado lo hace la goroutine de snapshots en su tic.
+func (s *ServidorHTTP) persistirUsuarios() {
+	ruta := s.configuracion.RutaArchivoUsuarios
+	if ruta == "" {
+		return
+	}
+	if time.Duration(s.configuracion.IntervaloSnapshot) > 0 {
+		s.usuariosPendientes.Store(true)
+		return
+	}
+	if err := s.guardarEnArchivo(ruta); err != nil {
+		s.registrador.Error("error al persistir usuarios", "error", err)
+	}
+}
+
+// iniciarSnapshotsPeriodicos arranca la goroutine que vuelca los cambios
+// pendientes al archivo de usuarios cada IntervaloSnapshot. Espaciar los
+// volcados amortiza el coste de reescribir el archivo completo cuando
+// las escrituras son frecuentes; a cambio, un corte abrupto puede perder
+// como mucho un intervalo de cambios. Sin ruta o sin intervalo no hace
+// nada: rige el volcado inmediato de persistirUsuarios.
+func (s *ServidorHTTP) iniciarSnapshotsPeriodicos() {
+	intervalo := time.Duration(s.configuracion.IntervaloSnapshot)
+	if s.configuracion.RutaArchivoUsuarios == "" || intervalo <= 0 {
+		return
+	}
+	s.detenerSnapshots = make(chan struct{})
+	s.snapshotsTerminados = make(chan struct{})
+	go func() {
+		defer close(s.snapshotsTerminados)
+		tic := time.NewTicker(intervalo)
+		defer tic.Stop()
+		for {
+			select {
+			case <-tic.C:
+				s.volcarSnapshotPendiente()
+			case <-s.detenerSnapshots:
+				// Volcado final para no perder las escrituras del cierre.
+				s.volcarSnapshotPendiente()
+				return
+			}
+		}
+	}()
+}
+
+// volcarSnapshotPendiente escribe el snapshot solo si hubo cambios desde
+// el último volcado; si la escritura falla, el cambio queda pendiente
+// para reintentarlo en el siguiente tic.
+func (s *ServidorHTTP) volcarSnapshotPendiente() {
+	if !s.usuariosPendientes.CompareAndSwap(true, false) {
+		return
+	}
+	if err := s.guardarEnArchivo(s.configuracion.RutaArchivoUsuarios); err != nil {
+		s.usuariosPendientes.Store(true)
+		s.registrador.Error("error al volcar el snapshot de usuarios", "error", err)
+	}
+}
+
+// pararSnapshots detiene la goroutine de snapshots y espera a que
+// complete su volcado final. Es inofensivo sin snapshots activos y ante
+// llamadas repetidas.
+func (s *ServidorHTTP) pararSnapshots() {
+	if s.detenerSnapshots == nil {
+		return
+	}
+	s.apagarSnapshots.Do(func() {
+		close(s.detenerSnapshots)
+		<-s.snapshotsTerminados
+	})
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestPersistenciaEntreReinicios(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "usuarios.json")
+
+	primero := NuevoServidor(&Configuracion{RutaArchivoUsuarios: ruta})
+	crearUsuarioDePrueba(t, primero, "Ana", "ana@ejemplo.com")
+	crearUsuarioDePrueba(t, primero, "Juan", "juan@ejemplo.com")
+
+	// Un "reinicio": servidor nuevo con la misma ruta.
+	segundo := NuevoServidor(&Configuracion{RutaArchivoUsuarios: ruta})
+	if usuarios := contarEnAlmacen(t, segundo.almacen); usuarios != 2 {
+		t.Fatalf("tras el reinicio deberían cargarse 2 usuarios, hay %d", usuarios)
+	}
+
+	// El contador debe reconstruirse como máximo+1: el siguiente usuario
+	// no reutiliza IDs.
+	tercero := crearUsuarioDePrueba(t, segundo, "Lucía", "lucia@ejemplo.com")
+	if tercero.ID != 3 {
+		t.Fatalf("el nuevo usuario debería recibir el ID 3, recibió %d", tercero.ID)
+	}
+}
+
+func TestPersistenciaTrasBorrado(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "usuarios.json")
+	servidor := NuevoServidor(&Configuracion{RutaArchivoUsuarios: ruta})
+	usuario := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodDelete, "/usuarios/1", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("no se pudo borrar el usuario %d: %d", usuario.ID, grabadora.Code)
+	}
+
+	recargado := NuevoServidor(&Configuracion{RutaArchivoUsuarios: ruta})
+	if total := contarEnAlmacen(t, recargado.almacen); total != 0 {
+		t.Fatalf("el borrado debería persistirse, quedan %d usuarios", total)
+	}
+}
+
+func TestSnapshotsPeriodicos(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "usuarios.json")
+	servidor := NuevoServidor(&Configuracion{
+		RutaArchivoUsuarios: ruta,
+		IntervaloSnapshot:   Duracion(20 * time.Millisecond),
+	})
+	servidor.iniciarSnapshotsPeriodicos()
+
+	// La escritura solo marca el cambio; el tic siguiente lo vuelca.
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	esperarCondicion(t, time.Second, func() bool {
+		datos, err := os.ReadFile(ruta)
+		return err == nil && strings.Contains(string(datos), "ana@ejemplo.com")
+	})
+
+	// Al parar se vuelca lo pendiente aunque no haya llegado otro tic.
+	crearUsuarioDePrueba(t, servidor, "Juan", "juan@ejemplo.com")
+	servidor.pararSnapshots()
+	datos, err := os.ReadFile(ruta)
+	if err != nil {
+		t.Fatalf("no se pudo leer el snapshot: %v", err)
+	}
+	if !strings.Contains(string(datos), "juan@ejemplo.com") {
+		t.Fatalf("el volcado final debería incluir la última escritura: %s", datos)
+	}
+
+	// Un "reinicio" repone la lista completa desde el snapshot.
+	segundo := NuevoServidor(&Configuracion{RutaArchivoUsuarios: ruta})
+	if total := contarEnAlmacen(t, segundo.almacen); total != 2 {
+		t.Fatalf("tras el reinicio deberían cargarse 2 usuarios, hay %d", total)
+	}
+}
+
+func TestSinIntervaloSeVuelcaEnCadaEscritura(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "usuarios.json")
+	servidor := NuevoServidor(&Configuracion{RutaArchivoUsuarios: ruta})
+
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	if _, err := os.Stat(ruta); err != nil {
+		t.Fatalf("sin intervalo configurado, la escritura debería volcar de inmediato: %v", err)
+	}
+}
+
+func TestCargarDesdeArchivoInexistente(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	if err := servidor.cargarDesdeArchivo(filepath.Join(t.TempDir(), "no-existe.json")); err != nil {
+		t.Fatalf("un archivo inexistente no debería ser un error: %v", err)
+	}
+}
+
+func TestCargarDesdeArchivoMalformado(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "roto.json")
+	if err := os.WriteFile(ruta, []byte("{no es json"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	servidor := NuevoServidor(&Configuracion{})
+	if err := servidor.cargarDesdeArchivo(ruta); err == nil {
+		t.Fatal("un archivo malformado debería devolver error")
+	}
+}
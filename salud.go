@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+)
+
+// Verificacion es una comprobación de salud con nombre que puede fallar
+// con un error descriptivo.
+type Verificacion struct {
+	Nombre    string
+	Comprobar func() error
+}
+
+// RegistrarVerificacion añade una comprobación al health check detallado.
+func (s *ServidorHTTP) RegistrarVerificacion(nombre string, comprobar func() error) {
+	s.verificaciones = append(s.verificaciones, Verificacion{Nombre: nombre, Comprobar: comprobar})
+}
+
+// verificarEscrituraAlmacen confirma que el almacenamiento acepta
+// escrituras reales. Un chequeo de solo lectura puede pasar mientras las
+// escrituras fallan (disco lleno, permisos); la prueba concreta la hace
+// cada implementación del almacén.
+func (s *ServidorHTTP) verificarEscrituraAlmacen() error {
+	return s.almacen.VerificarEscritura()
+}
+
+// ejecutarVerificaciones corre todas las comprobaciones registradas y
+// devuelve el detalle por dependencia junto con el veredicto global.
+func (s *ServidorHTTP) ejecutarVerificaciones() (map[string]string, bool) {
+	detalle := map[string]string{}
+	saludable := true
+	for _, verificacion := range s.verificaciones {
+		if err := verificacion.Comprobar(); err != nil {
+			detalle[verificacion.Nombre] = err.Error()
+			saludable = false
+		} else {
+			detalle[verificacion.Nombre] = "ok"
+		}
+	}
+	return detalle, saludable
+}
+
+// manejarSaludDetallado ejecuta todas las verificaciones registradas y
+// responde 503 si alguna falla, con el detalle por verificación.
+func (s *ServidorHTTP) manejarSaludDetallado(w http.ResponseWriter, r *http.Request) {
+	detalle, saludable := s.ejecutarVerificaciones()
+
+	if !saludable {
+		enviarRespuestaJSON(w, http.StatusServiceUnavailable, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "Alguna verificación de salud falló",
+			CodigoError: CodigoServicioNoDisponible,
+			Datos:       detalle,
+		})
+		return
+	}
+
+	enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+		Exitoso: true,
+		Mensaje: "saludable",
+		Datos:   detalle,
+	})
+}
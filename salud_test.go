@@ -0,0 +1,111 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSaludDetalladaConEscrituraSana(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarSaludDetallado(grabadora, httptest.NewRequest(http.MethodGet, "/salud/detallado", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+	}
+	if !strings.Contains(grabadora.Body.String(), `"escritura_almacen":"ok"`) {
+		t.Fatalf("falta el detalle de la verificación de escritura: %s", grabadora.Body.String())
+	}
+
+	// La escritura de prueba no debe dejar rastro.
+	if total := contarEnAlmacen(t, servidor.almacen); total != 1 {
+		t.Fatalf("la verificación dejó registros residuales: %d usuarios", total)
+	}
+}
+
+func TestDrenajeRetiraLaInstanciaDelBalanceador(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	// Antes del drenaje la instancia está lista.
+	grabadora := httptest.NewRecorder()
+	servidor.manejarListo(grabadora, httptest.NewRequest(http.MethodGet, "/listo", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("antes de drenar /listo debería dar 200, dio %d: %s", grabadora.Code, grabadora.Body.String())
+	}
+
+	grabadora = httptest.NewRecorder()
+	servidor.manejarDrenar(grabadora, httptest.NewRequest(http.MethodPost, "/admin/drenar", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("activar el drenaje debería dar 200, dio %d", grabadora.Code)
+	}
+
+	// Readiness cae para que el balanceador retire el tráfico...
+	grabadora = httptest.NewRecorder()
+	servidor.manejarListo(grabadora, httptest.NewRequest(http.MethodGet, "/listo", nil))
+	if grabadora.Code != http.StatusServiceUnavailable {
+		t.Fatalf("durante el drenaje /listo debería dar 503, dio %d", grabadora.Code)
+	}
+
+	// ...pero el liveness sigue sano: el proceso no debe reiniciarse.
+	grabadora = httptest.NewRecorder()
+	servidor.manejarSalud(grabadora, httptest.NewRequest(http.MethodGet, "/salud", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("durante el drenaje /salud debería seguir en 200, dio %d", grabadora.Code)
+	}
+
+	// El estado lo refleja y activarlo otra vez es idempotente.
+	grabadora = httptest.NewRecorder()
+	servidor.manejarEstado(grabadora, httptest.NewRequest(http.MethodGet, "/estado", nil))
+	if !strings.Contains(grabadora.Body.String(), `"drenando":true`) {
+		t.Fatalf("/estado debería reportar el drenaje: %s", grabadora.Body.String())
+	}
+	grabadora = httptest.NewRecorder()
+	servidor.manejarDrenar(grabadora, httptest.NewRequest(http.MethodPost, "/admin/drenar", nil))
+	if grabadora.Code != http.StatusOK || !strings.Contains(grabadora.Body.String(), "ya estaba activado") {
+		t.Fatalf("repetir el drenaje debería ser idempotente: %d %s", grabadora.Code, grabadora.Body.String())
+	}
+}
+
+func TestSaludDetalladaConAlmacenDeSoloLectura(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	servidor.verificaciones = nil
+	servidor.RegistrarVerificacion("escritura_almacen", func() error {
+		return errors.New("almacenamiento de solo lectura")
+	})
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarSaludDetallado(grabadora, httptest.NewRequest(http.MethodGet, "/salud/detallado", nil))
+	if grabadora.Code != http.StatusServiceUnavailable {
+		t.Fatalf("con escrituras rotas debería dar 503, dio %d", grabadora.Code)
+	}
+	if !strings.Contains(grabadora.Body.String(), "solo lectura") {
+		t.Fatalf("el detalle debería incluir la causa: %s", grabadora.Body.String())
+	}
+}
+
+func TestListoReportaDependenciaCaida(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	servidor.RegistrarVerificacion("base_de_datos", func() error {
+		return errors.New("conexión rechazada")
+	})
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarListo(grabadora, httptest.NewRequest(http.MethodGet, "/listo", nil))
+	if grabadora.Code != http.StatusServiceUnavailable {
+		t.Fatalf("con una dependencia caída /listo debería dar 503, dio %d", grabadora.Code)
+	}
+	if !strings.Contains(grabadora.Body.String(), `"base_de_datos":"conexión rechazada"`) {
+		t.Fatalf("el 503 debería detallar la dependencia caída: %s", grabadora.Body.String())
+	}
+
+	// El liveness de /salud sigue siendo un chequeo simple del proceso.
+	grabadora = httptest.NewRecorder()
+	servidor.manejarSalud(grabadora, httptest.NewRequest(http.MethodGet, "/salud", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("/salud no debería depender de las verificaciones, dio %d", grabadora.Code)
+	}
+}
@@ -0,0 +1,2081 @@
+package main
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestInicializacionPerezosaDelAlmacen(t *testing.T) {
+	var intentos atomic.Int32
+	servidor := NuevoServidor(&Configuracion{}).ConInicializadorDeAlmacen(func() error {
+		if intentos.Add(1) < 3 {
+			return errors.New("almacenamiento todavía no disponible")
+		}
+		return nil
+	})
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarListo(grabadora, httptest.NewRequest(http.MethodGet, "/listo", nil))
+	if grabadora.Code != http.StatusServiceUnavailable {
+		t.Fatalf("antes de inicializar, /listo debería dar 503, dio %d", grabadora.Code)
+	}
+
+	grabadora = httptest.NewRecorder()
+	servidor.manejarUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+	if grabadora.Code != http.StatusServiceUnavailable {
+		t.Fatalf("las rutas de datos deberían dar 503 sin almacenamiento, dieron %d", grabadora.Code)
+	}
+
+	servidor.prepararAlmacen()
+
+	if n := intentos.Load(); n != 3 {
+		t.Fatalf("se esperaban 3 intentos de inicialización, hubo %d", n)
+	}
+
+	esperarCondicion(t, time.Second, func() bool { return servidor.almacenListo.Load() })
+
+	grabadora = httptest.NewRecorder()
+	servidor.manejarListo(grabadora, httptest.NewRequest(http.MethodGet, "/listo", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("tras inicializar, /listo debería dar 200, dio %d", grabadora.Code)
+	}
+
+	grabadora = httptest.NewRecorder()
+	servidor.manejarUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("tras inicializar, /usuarios debería dar 200, dio %d", grabadora.Code)
+	}
+}
+
+func TestServidorSinInicializadorEstaListo(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	grabadora := httptest.NewRecorder()
+	servidor.manejarListo(grabadora, httptest.NewRequest(http.MethodGet, "/listo", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("sin inicializador, /listo debería dar 200, dio %d", grabadora.Code)
+	}
+}
+
+func TestEstadoReportaCaracteristicasActivas(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{
+		LimitePeticionesPorMinuto: 100,
+		ModoDebug:                 true,
+	})
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarEstado(grabadora, httptest.NewRequest(http.MethodGet, "/estado", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+	}
+
+	var respuesta struct {
+		Datos struct {
+			Caracteristicas map[string]bool `json:"caracteristicas"`
+		} `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatalf("respuesta inválida: %v", err)
+	}
+
+	caracteristicas := respuesta.Datos.Caracteristicas
+	if !caracteristicas["limite_tasa"] || !caracteristicas["modo_debug"] {
+		t.Fatalf("características activas mal reportadas: %v", caracteristicas)
+	}
+	if caracteristicas["bloqueo_desechables"] {
+		t.Fatalf("bloqueo_desechables debería estar inactivo: %v", caracteristicas)
+	}
+}
+
+func TestEstadoReportaUptimeYConteos(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "Luis", "luis@ejemplo.com")
+	servidor.metricas.registrar(http.MethodGet, http.StatusOK, time.Millisecond)
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarEstado(grabadora, httptest.NewRequest(http.MethodGet, "/estado", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+	}
+
+	var respuesta struct {
+		Datos struct {
+			UptimeSegundos      float64            `json:"uptime_segundos"`
+			PeticionesAtendidas int64              `json:"peticiones_atendidas"`
+			UsuariosEnMemoria   int                `json:"usuarios_en_memoria"`
+			Memoria             map[string]float64 `json:"memoria"`
+		} `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatalf("respuesta inválida: %v", err)
+	}
+
+	datos := respuesta.Datos
+	if datos.UptimeSegundos <= 0 {
+		t.Fatalf("el uptime debería ser positivo, fue %v", datos.UptimeSegundos)
+	}
+	if datos.UsuariosEnMemoria != 2 {
+		t.Fatalf("conteo de usuarios esperado 2, obtenido %d", datos.UsuariosEnMemoria)
+	}
+	if datos.PeticionesAtendidas != 1 {
+		t.Fatalf("peticiones atendidas esperadas 1, obtenidas %d", datos.PeticionesAtendidas)
+	}
+	if datos.Memoria["heap_bytes"] <= 0 {
+		t.Fatalf("falta el uso de memoria: %v", datos.Memoria)
+	}
+}
+
+func TestManejarRetraso(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{ModoDebug: true})
+
+	t.Run("respeta el retraso pedido", func(t *testing.T) {
+		inicio := time.Now()
+		grabadora := httptest.NewRecorder()
+		servidor.manejarRetraso(grabadora, httptest.NewRequest(http.MethodGet, "/estado/retraso?ms=50", nil))
+		if transcurrido := time.Since(inicio); transcurrido < 50*time.Millisecond {
+			t.Fatalf("respondió en %v, antes del retraso pedido", transcurrido)
+		}
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+	})
+
+	t.Run("aborta al cancelar el contexto", func(t *testing.T) {
+		ctx, cancelar := context.WithCancel(context.Background())
+		peticion := httptest.NewRequest(http.MethodGet, "/estado/retraso?ms=5000", nil).WithContext(ctx)
+
+		terminado := make(chan struct{})
+		inicio := time.Now()
+		go func() {
+			defer close(terminado)
+			servidor.manejarRetraso(httptest.NewRecorder(), peticion)
+		}()
+		cancelar()
+		<-terminado
+		if transcurrido := time.Since(inicio); transcurrido > time.Second {
+			t.Fatalf("la cancelación tardó %v en surtir efecto", transcurrido)
+		}
+	})
+
+	t.Run("rechaza ms inválido", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.manejarRetraso(grabadora, httptest.NewRequest(http.MethodGet, "/estado/retraso?ms=abc", nil))
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("código esperado 400, obtenido %d", grabadora.Code)
+		}
+	})
+}
+
+func TestManejarMemoriaUsuarios(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	servidor.almacen.Reemplazar(usuariosDePrueba(250))
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarMemoriaUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/estado/memoria", nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+	}
+
+	var respuesta struct {
+		Datos struct {
+			TotalUsuarios   int `json:"total_usuarios"`
+			Muestra         int `json:"muestra"`
+			BytesPorUsuario int `json:"bytes_por_usuario"`
+			BytesTotales    int `json:"bytes_total_estimados"`
+		} `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatalf("respuesta inválida: %v", err)
+	}
+	datos := respuesta.Datos
+	if datos.TotalUsuarios != 250 || datos.Muestra != 100 {
+		t.Fatalf("conteos inesperados: %+v", datos)
+	}
+	if datos.BytesPorUsuario <= 0 || datos.BytesTotales < datos.BytesPorUsuario*250 {
+		t.Fatalf("estimaciones implausibles: %+v", datos)
+	}
+}
+
+// crearUsuarioDePrueba da de alta un usuario vía el handler y devuelve el
+// usuario creado.
+func crearUsuarioDePrueba(t *testing.T, servidor *ServidorHTTP, nombre, email string) Usuario {
+	t.Helper()
+	cuerpo := strings.NewReader(`{"nombre":"` + nombre + `","email":"` + email + `"}`)
+	grabadora := httptest.NewRecorder()
+	servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+	if grabadora.Code != http.StatusCreated {
+		t.Fatalf("no se pudo crear el usuario de prueba: %d %s", grabadora.Code, grabadora.Body.String())
+	}
+	var respuesta struct {
+		Datos Usuario `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatal(err)
+	}
+	return respuesta.Datos
+}
+
+func TestValidacionDelNombreAlCrear(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{LongitudMaximaNombre: 10})
+	crear := func(nombre string) *httptest.ResponseRecorder {
+		cuerpo := strings.NewReader(`{"nombre":"` + nombre + `","email":"nombre@ejemplo.com"}`)
+		grabadora := httptest.NewRecorder()
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+		return grabadora
+	}
+
+	t.Run("solo espacios da 400", func(t *testing.T) {
+		grabadora := crear("   ")
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un nombre de solo espacios debería dar 400, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("demasiado largo da 400", func(t *testing.T) {
+		grabadora := crear(strings.Repeat("a", 11))
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un nombre sobre el máximo debería dar 400, dio %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), "10 caracteres") {
+			t.Fatalf("el mensaje debería citar el máximo: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("se almacena recortado", func(t *testing.T) {
+		grabadora := crear("  Ana  ")
+		if grabadora.Code != http.StatusCreated {
+			t.Fatalf("un nombre válido con espacios debería aceptarse, dio %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		var respuesta struct {
+			Datos Usuario `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if respuesta.Datos.Nombre != "Ana" {
+			t.Fatalf("el nombre almacenado debería ir recortado, fue %q", respuesta.Datos.Nombre)
+		}
+	})
+}
+
+func TestActualizarUsuarioConPUT(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	usuario := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	t.Run("actualización exitosa", func(t *testing.T) {
+		cuerpo := strings.NewReader(`{"id":999,"nombre":"Ana María","email":"anamaria@ejemplo.com"}`)
+		peticion := httptest.NewRequest(http.MethodPut, fmt.Sprintf("/usuarios/%d", usuario.ID), cuerpo)
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarioPorID(grabadora, peticion)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+
+		var respuesta struct {
+			Datos Usuario `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if respuesta.Datos.ID != usuario.ID {
+			t.Fatalf("el ID de la URL debe prevalecer: %d", respuesta.Datos.ID)
+		}
+		if respuesta.Datos.Nombre != "Ana María" || respuesta.Datos.Email != "anamaria@ejemplo.com" {
+			t.Fatalf("usuario no actualizado: %+v", respuesta.Datos)
+		}
+	})
+
+	t.Run("marca la fecha de actualización", func(t *testing.T) {
+		otro := crearUsuarioDePrueba(t, servidor, "Luis", "luis@ejemplo.com")
+		if otro.Actualizado != "" {
+			t.Fatalf("la creación no debería rellenar Actualizado: %q", otro.Actualizado)
+		}
+
+		cuerpo := strings.NewReader(`{"nombre":"Luis Alberto","email":"luis@ejemplo.com"}`)
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/usuarios/%d", otro.ID), cuerpo))
+
+		var respuesta struct {
+			Datos Usuario `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if respuesta.Datos.Actualizado == "" {
+			t.Fatal("el PUT debería establecer Actualizado")
+		}
+		if respuesta.Datos.Creado != otro.Creado {
+			t.Fatalf("el PUT no debería tocar Creado: %q vs %q", respuesta.Datos.Creado, otro.Creado)
+		}
+	})
+
+	t.Run("omite fecha_actualizado cuando está vacía", func(t *testing.T) {
+		otro := crearUsuarioDePrueba(t, servidor, "Sofía", "sofia@ejemplo.com")
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/usuarios/%d", otro.ID), nil))
+		if strings.Contains(grabadora.Body.String(), "fecha_actualizado") {
+			t.Fatalf("un usuario nunca modificado no debería serializar fecha_actualizado: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("usuario inexistente da 404", func(t *testing.T) {
+		cuerpo := strings.NewReader(`{"nombre":"Nadie","email":"nadie@ejemplo.com"}`)
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodPut, "/usuarios/999", cuerpo))
+		if grabadora.Code != http.StatusNotFound {
+			t.Fatalf("código esperado 404, obtenido %d", grabadora.Code)
+		}
+	})
+
+	t.Run("campos vacíos dan 400", func(t *testing.T) {
+		cuerpo := strings.NewReader(`{"nombre":"","email":""}`)
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/usuarios/%d", usuario.ID), cuerpo))
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("código esperado 400, obtenido %d", grabadora.Code)
+		}
+	})
+}
+
+// respuestaPaginada decodifica la respuesta paginada de /usuarios.
+type respuestaPaginada struct {
+	Datos struct {
+		Usuarios   []Usuario `json:"usuarios"`
+		Paginacion struct {
+			Total        int `json:"total"`
+			Pagina       int `json:"pagina"`
+			Tamano       int `json:"tamano"`
+			TotalPaginas int `json:"total_paginas"`
+		} `json:"paginacion"`
+	} `json:"datos"`
+}
+
+func TestPaginacionDeUsuarios(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	servidor.almacen.Reemplazar(usuariosDePrueba(45))
+
+	listar := func(query string) respuestaPaginada {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios"+query, nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+		var respuesta respuestaPaginada
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return respuesta
+	}
+
+	t.Run("primera página con valores por defecto", func(t *testing.T) {
+		respuesta := listar("")
+		if len(respuesta.Datos.Usuarios) != 20 || respuesta.Datos.Usuarios[0].ID != 1 {
+			t.Fatalf("primera página inesperada: %d usuarios", len(respuesta.Datos.Usuarios))
+		}
+		if respuesta.Datos.Paginacion.Total != 45 || respuesta.Datos.Paginacion.TotalPaginas != 3 {
+			t.Fatalf("metadatos inesperados: %+v", respuesta.Datos.Paginacion)
+		}
+	})
+
+	t.Run("página intermedia", func(t *testing.T) {
+		respuesta := listar("?pagina=2&tamano=10")
+		if len(respuesta.Datos.Usuarios) != 10 || respuesta.Datos.Usuarios[0].ID != 11 {
+			t.Fatalf("página intermedia inesperada: %+v", respuesta.Datos.Usuarios[0])
+		}
+	})
+
+	t.Run("página fuera de rango devuelve vacío con 200", func(t *testing.T) {
+		respuesta := listar("?pagina=99")
+		if len(respuesta.Datos.Usuarios) != 0 {
+			t.Fatalf("se esperaba lista vacía, hay %d", len(respuesta.Datos.Usuarios))
+		}
+	})
+
+	t.Run("valores inválidos caen a los por defecto", func(t *testing.T) {
+		respuesta := listar("?pagina=-3&tamano=abc")
+		if respuesta.Datos.Paginacion.Pagina != 1 || respuesta.Datos.Paginacion.Tamano != 20 {
+			t.Fatalf("los inválidos deberían caer a 1/20: %+v", respuesta.Datos.Paginacion)
+		}
+	})
+}
+
+func TestBusquedaDeUsuarios(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "María García", "maria@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "Juan Pérez", "juan@trabajo.net")
+	crearUsuarioDePrueba(t, servidor, "Marina López", "marina@ejemplo.com")
+
+	buscar := func(query string) respuestaPaginada {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios"+query, nil))
+		var respuesta respuestaPaginada
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return respuesta
+	}
+
+	t.Run("por nombre parcial case-insensitive", func(t *testing.T) {
+		respuesta := buscar("?buscar=mari")
+		if len(respuesta.Datos.Usuarios) != 2 {
+			t.Fatalf("se esperaban 2 coincidencias, hubo %d", len(respuesta.Datos.Usuarios))
+		}
+	})
+
+	t.Run("por email", func(t *testing.T) {
+		respuesta := buscar("?buscar=trabajo.net")
+		if len(respuesta.Datos.Usuarios) != 1 || respuesta.Datos.Usuarios[0].Nombre != "Juan Pérez" {
+			t.Fatalf("coincidencia por email inesperada: %+v", respuesta.Datos.Usuarios)
+		}
+	})
+
+	t.Run("sin coincidencias", func(t *testing.T) {
+		respuesta := buscar("?buscar=inexistente")
+		if len(respuesta.Datos.Usuarios) != 0 || respuesta.Datos.Paginacion.Total != 0 {
+			t.Fatalf("no debería haber coincidencias: %+v", respuesta.Datos)
+		}
+	})
+
+	t.Run("sin parámetro devuelve todos", func(t *testing.T) {
+		respuesta := buscar("")
+		if len(respuesta.Datos.Usuarios) != 3 {
+			t.Fatalf("se esperaban los 3 usuarios, hubo %d", len(respuesta.Datos.Usuarios))
+		}
+	})
+
+	t.Run("se combina con paginación", func(t *testing.T) {
+		respuesta := buscar("?buscar=mari&tamano=1&pagina=2")
+		if len(respuesta.Datos.Usuarios) != 1 || respuesta.Datos.Paginacion.Total != 2 {
+			t.Fatalf("búsqueda y paginación mal combinadas: %+v", respuesta.Datos)
+		}
+	})
+}
+
+func TestCodigosDeErrorEstables(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	codigoDe := func(t *testing.T, grabadora *httptest.ResponseRecorder) string {
+		t.Helper()
+		var respuesta RespuestaJSON
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return respuesta.CodigoError
+	}
+
+	t.Run("usuario inexistente", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios/999", nil))
+		if codigo := codigoDe(t, grabadora); codigo != CodigoUsuarioNoEncontrado {
+			t.Fatalf("código esperado %q, obtenido %q", CodigoUsuarioNoEncontrado, codigo)
+		}
+	})
+
+	t.Run("email inválido", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		cuerpo := strings.NewReader(`{"nombre":"Luis","email":"sin-arroba"}`)
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+		if codigo := codigoDe(t, grabadora); codigo != CodigoEmailInvalido {
+			t.Fatalf("código esperado %q, obtenido %q", CodigoEmailInvalido, codigo)
+		}
+	})
+
+	t.Run("email duplicado", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		cuerpo := strings.NewReader(`{"nombre":"Otra Ana","email":"ana@ejemplo.com"}`)
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+		if codigo := codigoDe(t, grabadora); codigo != CodigoEmailDuplicado {
+			t.Fatalf("código esperado %q, obtenido %q", CodigoEmailDuplicado, codigo)
+		}
+	})
+
+	t.Run("método no permitido", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuarios(grabadora, httptest.NewRequest(http.MethodDelete, "/usuarios", nil))
+		if codigo := codigoDe(t, grabadora); codigo != CodigoMetodoNoPermitido {
+			t.Fatalf("código esperado %q, obtenido %q", CodigoMetodoNoPermitido, codigo)
+		}
+	})
+}
+
+func TestCacheCondicionalConETag(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	usuario := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	ruta := fmt.Sprintf("/usuarios/%d", usuario.ID)
+
+	pedir := func(etag string) *httptest.ResponseRecorder {
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodGet, ruta, nil)
+		if etag != "" {
+			peticion.Header.Set("If-None-Match", etag)
+		}
+		servidor.manejarUsuarioPorID(grabadora, peticion)
+		return grabadora
+	}
+
+	primera := pedir("")
+	etag := primera.Header().Get("ETag")
+	if primera.Code != http.StatusOK || etag == "" {
+		t.Fatalf("la primera petición debería dar 200 con ETag, dio %d %q", primera.Code, etag)
+	}
+
+	segunda := pedir(etag)
+	if segunda.Code != http.StatusNotModified {
+		t.Fatalf("con el ETag vigente se esperaba 304, fue %d", segunda.Code)
+	}
+	if segunda.Body.Len() != 0 {
+		t.Fatalf("el 304 no debería llevar cuerpo: %s", segunda.Body.String())
+	}
+
+	// Tras actualizar al usuario el ETag viejo deja de valer.
+	cuerpo := strings.NewReader(`{"nombre":"Ana María","email":"ana@ejemplo.com"}`)
+	grabadora := httptest.NewRecorder()
+	servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodPut, ruta, cuerpo))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("no se pudo actualizar el usuario: %d", grabadora.Code)
+	}
+
+	tercera := pedir(etag)
+	if tercera.Code != http.StatusOK {
+		t.Fatalf("tras actualizar, el ETag viejo debería dar 200, dio %d", tercera.Code)
+	}
+	if nuevo := tercera.Header().Get("ETag"); nuevo == etag || nuevo == "" {
+		t.Fatalf("el ETag debería cambiar con la actualización: %q vs %q", etag, nuevo)
+	}
+}
+
+func TestFiltrarUsuariosPorRangoDeFechas(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	fechas := []string{"2024-01-05 10:00:00", "2024-01-20 18:30:00", "2024-03-01 09:00:00"}
+	usuarios := make([]Usuario, 0, len(fechas))
+	for i, fecha := range fechas {
+		usuarios = append(usuarios, Usuario{
+			ID:     i + 1,
+			Nombre: fmt.Sprintf("Usuario %d", i+1),
+			Email:  fmt.Sprintf("usuario%d@ejemplo.com", i+1),
+			Creado: fecha,
+		})
+	}
+	servidor.almacen.Reemplazar(usuarios)
+
+	listar := func(query string) *httptest.ResponseRecorder {
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios"+query, nil))
+		return grabadora
+	}
+	ids := func(t *testing.T, grabadora *httptest.ResponseRecorder) []int {
+		t.Helper()
+		var respuesta respuestaPaginada
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		resultado := make([]int, 0, len(respuesta.Datos.Usuarios))
+		for _, usuario := range respuesta.Datos.Usuarios {
+			resultado = append(resultado, usuario.ID)
+		}
+		return resultado
+	}
+
+	t.Run("rango completo inclusivo", func(t *testing.T) {
+		grabadora := listar("?desde=2024-01-05&hasta=2024-01-20")
+		if obtenidos := ids(t, grabadora); len(obtenidos) != 2 || obtenidos[0] != 1 || obtenidos[1] != 2 {
+			t.Fatalf("IDs esperados [1 2], obtenidos %v", obtenidos)
+		}
+	})
+
+	t.Run("solo desde es rango abierto", func(t *testing.T) {
+		grabadora := listar("?desde=2024-01-10")
+		if obtenidos := ids(t, grabadora); len(obtenidos) != 2 || obtenidos[0] != 2 || obtenidos[1] != 3 {
+			t.Fatalf("IDs esperados [2 3], obtenidos %v", obtenidos)
+		}
+	})
+
+	t.Run("se combina con búsqueda y paginación", func(t *testing.T) {
+		grabadora := listar("?desde=2024-01-01&hasta=2024-03-31&buscar=usuario&tamano=2&pagina=2")
+		if obtenidos := ids(t, grabadora); len(obtenidos) != 1 || obtenidos[0] != 3 {
+			t.Fatalf("IDs esperados [3], obtenidos %v", obtenidos)
+		}
+	})
+
+	t.Run("fecha malformada da 400", func(t *testing.T) {
+		grabadora := listar("?desde=ayer")
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("una fecha malformada debería dar 400, dio %d", grabadora.Code)
+		}
+	})
+}
+
+func TestFechaDeCreacionEnRFC3339UTC(t *testing.T) {
+	t.Run("por defecto es RFC3339 en UTC", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		usuario := crearUsuarioDePrueba(t, servidor, "María García", "maria@ejemplo.com")
+
+		fecha, err := time.Parse(time.RFC3339, usuario.Creado)
+		if err != nil {
+			t.Fatalf("fecha_creado debería ser RFC3339, llegó %q: %v", usuario.Creado, err)
+		}
+		if _, desplazamiento := fecha.Zone(); desplazamiento != 0 {
+			t.Fatalf("fecha_creado debería estar en UTC, llegó %q", usuario.Creado)
+		}
+	})
+
+	t.Run("respeta el formato configurado", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{FormatoFecha: "2006-01-02 15:04:05"})
+		usuario := crearUsuarioDePrueba(t, servidor, "Juan Pérez", "juan@ejemplo.com")
+
+		if _, err := time.Parse("2006-01-02 15:04:05", usuario.Creado); err != nil {
+			t.Fatalf("fecha_creado debería seguir el formato configurado, llegó %q: %v", usuario.Creado, err)
+		}
+	})
+
+	t.Run("el filtro por rango entiende fechas RFC3339", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		crearUsuarioDePrueba(t, servidor, "Marina López", "marina@ejemplo.com")
+
+		grabadora := httptest.NewRecorder()
+		hoy := time.Now().UTC().Format(formatoFechaFiltro)
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios?desde="+hoy+"&hasta="+hoy, nil))
+
+		var respuesta respuestaPaginada
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if len(respuesta.Datos.Usuarios) != 1 {
+			t.Fatalf("el usuario creado hoy debería caer en el rango de hoy, llegaron %d", len(respuesta.Datos.Usuarios))
+		}
+	})
+}
+
+func TestContarUsuarios(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "María García", "maria@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "Juan Pérez", "juan@trabajo.net")
+	crearUsuarioDePrueba(t, servidor, "Marina López", "marina@ejemplo.com")
+	rutas := servidor.configurarRutas()
+
+	contar := func(t *testing.T, query string) int {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		rutas.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/api/v1/usuarios/contar"+query, nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		var respuesta struct {
+			Datos struct {
+				Total int `json:"total"`
+			} `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return respuesta.Datos.Total
+	}
+
+	if total := contar(t, ""); total != 3 {
+		t.Fatalf("conteo sin filtro esperado 3, obtenido %d", total)
+	}
+	if total := contar(t, "?buscar=mari"); total != 2 {
+		t.Fatalf("conteo con búsqueda esperado 2, obtenido %d", total)
+	}
+	if total := contar(t, "?hasta=2000-01-01"); total != 0 {
+		t.Fatalf("conteo fuera de rango esperado 0, obtenido %d", total)
+	}
+}
+
+func TestEliminarUsuarioConDELETE(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	primero := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	segundo := crearUsuarioDePrueba(t, servidor, "Juan", "juan@ejemplo.com")
+	tercero := crearUsuarioDePrueba(t, servidor, "Lucía", "lucia@ejemplo.com")
+
+	grabadora := httptest.NewRecorder()
+	servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodDelete, fmt.Sprintf("/usuarios/%d", segundo.ID), nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+	}
+
+	restantes, err := servidor.almacen.ObtenerTodos()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(restantes) != 2 {
+		t.Fatalf("deberían quedar 2 usuarios, quedan %d", len(restantes))
+	}
+	if restantes[0].ID != primero.ID || restantes[1].ID != tercero.ID {
+		t.Fatalf("el borrado debe conservar el orden: %+v", restantes)
+	}
+}
+
+func TestEliminarUsuarioInexistente(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	grabadora := httptest.NewRecorder()
+	servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodDelete, "/usuarios/42", nil))
+	if grabadora.Code != http.StatusNotFound {
+		t.Fatalf("código esperado 404, obtenido %d", grabadora.Code)
+	}
+}
+
+func TestApagarConTimeoutFuerzaElCierre(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	escucha, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	colgado := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		servidor.peticionesEnVuelo.Add(1)
+		defer servidor.peticionesEnVuelo.Add(-1)
+		time.Sleep(5 * time.Second)
+	})
+	servidor.servidorWeb = &http.Server{Handler: colgado}
+	go servidor.servidorWeb.Serve(escucha)
+
+	// Deja una petición colgada que impide el Shutdown ordenado.
+	go http.Get("http://" + escucha.Addr().String() + "/")
+	esperarCondicion(t, time.Second, func() bool { return servidor.peticionesEnVuelo.Load() > 0 })
+
+	inicio := time.Now()
+	if err := servidor.apagarConTimeout(100 * time.Millisecond); err != nil {
+		t.Fatalf("el cierre forzado devolvió error: %v", err)
+	}
+	if transcurrido := time.Since(inicio); transcurrido > 2*time.Second {
+		t.Fatalf("el apagado tardó %v; el cierre forzado no actuó", transcurrido)
+	}
+}
+
+func TestCargarConfiguracionDesdeArchivo(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "config.json")
+	contenido := `{"puerto": 9090, "nombre_servicio": "joker-pruebas", "modo_debug": true}`
+	if err := os.WriteFile(ruta, []byte(contenido), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := cargarConfiguracion(ruta)
+	if err != nil {
+		t.Fatalf("cargarConfiguracion devolvió error: %v", err)
+	}
+	if config.Puerto != 9090 || config.NombreServicio != "joker-pruebas" || !config.ModoDebug {
+		t.Fatalf("el archivo no se aplicó: %+v", config)
+	}
+	if config.DireccionServidor != "0.0.0.0" {
+		t.Fatalf("los campos ausentes deberían conservar el valor por defecto: %+v", config)
+	}
+
+	// Las variables de entorno tienen prioridad sobre el archivo.
+	t.Setenv("PUERTO", "7070")
+	config, err = cargarConfiguracion(ruta)
+	if err != nil {
+		t.Fatalf("cargarConfiguracion con override devolvió error: %v", err)
+	}
+	if config.Puerto != 7070 {
+		t.Fatalf("el entorno debería pisar al archivo, puerto = %d", config.Puerto)
+	}
+}
+
+func TestTiempoEsperaComoDuracion(t *testing.T) {
+	cargar := func(t *testing.T, tiempoEspera string) *Configuracion {
+		t.Helper()
+		ruta := filepath.Join(t.TempDir(), "config.json")
+		if err := os.WriteFile(ruta, []byte(`{"tiempo_espera": `+tiempoEspera+`}`), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		config, err := cargarConfiguracion(ruta)
+		if err != nil {
+			t.Fatalf("cargarConfiguracion devolvió error: %v", err)
+		}
+		return config
+	}
+
+	t.Run("duración con sufijo", func(t *testing.T) {
+		config := cargar(t, `"1m500ms"`)
+		if time.Duration(config.TiempoEspera) != time.Minute+500*time.Millisecond {
+			t.Fatalf("duración inesperada: %v", time.Duration(config.TiempoEspera))
+		}
+	})
+
+	t.Run("entero plano son segundos", func(t *testing.T) {
+		config := cargar(t, `45`)
+		if time.Duration(config.TiempoEspera) != 45*time.Second {
+			t.Fatalf("45 debería interpretarse como segundos, fue %v", time.Duration(config.TiempoEspera))
+		}
+	})
+
+	t.Run("valor malformado cae al por defecto", func(t *testing.T) {
+		config := cargar(t, `"treinta segundos"`)
+		if time.Duration(config.TiempoEspera) != 30*time.Second {
+			t.Fatalf("un valor malformado debería conservar el por defecto, fue %v", time.Duration(config.TiempoEspera))
+		}
+	})
+
+	t.Run("la variable de entorno acepta ambos formatos", func(t *testing.T) {
+		t.Setenv("TIEMPO_ESPERA", "250ms")
+		config := obtenerConfiguracionDesdeEntorno()
+		if time.Duration(config.TiempoEspera) != 250*time.Millisecond {
+			t.Fatalf("duración inesperada desde el entorno: %v", time.Duration(config.TiempoEspera))
+		}
+	})
+}
+
+func TestTimeoutsSeparadosDelServidorWeb(t *testing.T) {
+	t.Run("los valores configurados se asignan al http.Server", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{
+			TiempoEspera:            Duracion(30 * time.Second),
+			TiempoLecturaServidor:   Duracion(5 * time.Second),
+			TiempoEscrituraServidor: Duracion(10 * time.Minute),
+			TiempoInactividad:       Duracion(90 * time.Second),
+			TiempoLecturaCabeceras:  Duracion(2 * time.Second),
+		})
+		web := servidor.construirServidorWeb("127.0.0.1:0")
+		if web.ReadTimeout != 5*time.Second {
+			t.Fatalf("ReadTimeout esperado 5s, fue %v", web.ReadTimeout)
+		}
+		if web.WriteTimeout != 10*time.Minute {
+			t.Fatalf("WriteTimeout esperado 10m, fue %v", web.WriteTimeout)
+		}
+		if web.IdleTimeout != 90*time.Second {
+			t.Fatalf("IdleTimeout esperado 90s, fue %v", web.IdleTimeout)
+		}
+		if web.ReadHeaderTimeout != 2*time.Second {
+			t.Fatalf("ReadHeaderTimeout esperado 2s, fue %v", web.ReadHeaderTimeout)
+		}
+	})
+
+	t.Run("sin configurar heredan TiempoEspera o el por defecto", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{TiempoEspera: Duracion(30 * time.Second)})
+		web := servidor.construirServidorWeb("127.0.0.1:0")
+		if web.ReadTimeout != 30*time.Second || web.WriteTimeout != 30*time.Second {
+			t.Fatalf("lectura y escritura deberían heredar TiempoEspera: %v / %v", web.ReadTimeout, web.WriteTimeout)
+		}
+		if web.IdleTimeout != tiempoInactividadPorDefecto {
+			t.Fatalf("IdleTimeout esperado %v, fue %v", tiempoInactividadPorDefecto, web.IdleTimeout)
+		}
+		if web.ReadHeaderTimeout != tiempoLecturaCabecerasPorDefecto {
+			t.Fatalf("ReadHeaderTimeout esperado %v, fue %v", tiempoLecturaCabecerasPorDefecto, web.ReadHeaderTimeout)
+		}
+	})
+}
+
+func TestCargarConfiguracionArchivoMalformado(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(ruta, []byte("{puerto:"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cargarConfiguracion(ruta); err == nil {
+		t.Fatal("un archivo malformado debería devolver error")
+	}
+}
+
+func TestCargarConfiguracionPuertoFueraDeRango(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(ruta, []byte(`{"puerto": 70000}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	_, err := cargarConfiguracion(ruta)
+	if err == nil || !strings.Contains(err.Error(), "fuera de rango") {
+		t.Fatalf("se esperaba un error de puerto fuera de rango, fue: %v", err)
+	}
+}
+
+func TestCargarConfiguracionSinRuta(t *testing.T) {
+	config, err := cargarConfiguracion("")
+	if err != nil {
+		t.Fatalf("sin ruta debería funcionar con los valores por defecto: %v", err)
+	}
+	if config.Puerto != 8080 {
+		t.Fatalf("puerto por defecto esperado 8080, obtenido %d", config.Puerto)
+	}
+}
+
+func TestActualizarUsuarioConPATCH(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	usuario := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	parchar := func(t *testing.T, cuerpo string) (*httptest.ResponseRecorder, Usuario) {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/usuarios/%d", usuario.ID), strings.NewReader(cuerpo))
+		servidor.manejarUsuarioPorID(grabadora, peticion)
+		var respuesta struct {
+			Datos Usuario `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return grabadora, respuesta.Datos
+	}
+
+	t.Run("solo el nombre", func(t *testing.T) {
+		grabadora, datos := parchar(t, `{"nombre":"Ana María"}`)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		if datos.Nombre != "Ana María" || datos.Email != "ana@ejemplo.com" {
+			t.Fatalf("el email no debería cambiar: %+v", datos)
+		}
+		if datos.Actualizado == "" {
+			t.Fatal("el PATCH debería marcar Actualizado")
+		}
+	})
+
+	t.Run("solo el email", func(t *testing.T) {
+		grabadora, datos := parchar(t, `{"email":"anamaria@ejemplo.com"}`)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		if datos.Email != "anamaria@ejemplo.com" || datos.Nombre != "Ana María" {
+			t.Fatalf("el nombre no debería cambiar: %+v", datos)
+		}
+	})
+
+	t.Run("email inválido da 400 solo si viene", func(t *testing.T) {
+		grabadora, _ := parchar(t, `{"email":"no-es-email"}`)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un email inválido debería dar 400, dio %d", grabadora.Code)
+		}
+		// Un patch sin email no debe disparar la validación de email.
+		grabadora, _ = parchar(t, `{"nombre":"Ana"}`)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("un patch solo de nombre debería pasar, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("patch vacío da 400", func(t *testing.T) {
+		grabadora, _ := parchar(t, `{}`)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un patch sin campos debería dar 400, dio %d", grabadora.Code)
+		}
+	})
+}
+
+func TestActualizarUsuarioConMergePatch(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	usuario := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	parchar := func(t *testing.T, tipoContenido, cuerpo string) (*httptest.ResponseRecorder, Usuario) {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/usuarios/%d", usuario.ID), strings.NewReader(cuerpo))
+		peticion.Header.Set("Content-Type", tipoContenido)
+		servidor.manejarUsuarioPorID(grabadora, peticion)
+		var respuesta struct {
+			Datos Usuario `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return grabadora, respuesta.Datos
+	}
+
+	t.Run("un campo modificado y otro intacto", func(t *testing.T) {
+		grabadora, datos := parchar(t, "application/merge-patch+json", `{"nombre":"Ana María"}`)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		if datos.Nombre != "Ana María" || datos.Email != "ana@ejemplo.com" {
+			t.Fatalf("solo el nombre debería cambiar: %+v", datos)
+		}
+	})
+
+	t.Run("null explícito borra el campo", func(t *testing.T) {
+		grabadora, datos := parchar(t, "application/merge-patch+json; charset=utf-8", `{"nombre":null}`)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		if datos.Nombre != "" {
+			t.Fatalf("un null explícito debería borrar el nombre, quedó %q", datos.Nombre)
+		}
+		if datos.Email != "ana@ejemplo.com" {
+			t.Fatalf("el email ausente debería conservarse: %+v", datos)
+		}
+	})
+
+	t.Run("metadatos se reemplazan y null los borra", func(t *testing.T) {
+		grabadora, datos := parchar(t, "application/merge-patch+json", `{"metadatos":{"telefono":"555-0100"}}`)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		if datos.Metadatos["telefono"] != "555-0100" {
+			t.Fatalf("los metadatos deberían reemplazarse: %+v", datos.Metadatos)
+		}
+
+		grabadora, datos = parchar(t, "application/merge-patch+json", `{"metadatos":null}`)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		if len(datos.Metadatos) != 0 {
+			t.Fatalf("un null explícito debería borrar los metadatos: %+v", datos.Metadatos)
+		}
+	})
+
+	t.Run("campo desconocido da 400", func(t *testing.T) {
+		grabadora, _ := parchar(t, "application/merge-patch+json", `{"nombrre":"Ana"}`)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un campo desconocido debería dar 400, dio %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), "no se reconoce") {
+			t.Fatalf("cuerpo inesperado: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("Content-Type desconocido da 415", func(t *testing.T) {
+		grabadora, _ := parchar(t, "text/plain", `{"nombre":"Ana"}`)
+		if grabadora.Code != http.StatusUnsupportedMediaType {
+			t.Fatalf("un Content-Type no soportado debería dar 415, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("email inválido en el patch da 400", func(t *testing.T) {
+		grabadora, _ := parchar(t, "application/merge-patch+json", `{"email":"no-es-email"}`)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un email inválido debería dar 400, dio %d", grabadora.Code)
+		}
+	})
+}
+
+func TestEnlacesHATEOASEnPaginacion(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	servidor.almacen.Reemplazar(usuariosDePrueba(45))
+
+	enlaces := func(t *testing.T, query string) map[string]string {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "http://api.ejemplo.com/usuarios"+query, nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+		var respuesta struct {
+			Datos struct {
+				Enlaces map[string]string `json:"enlaces"`
+			} `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return respuesta.Datos.Enlaces
+	}
+
+	t.Run("primera página con siguiente y sin anterior", func(t *testing.T) {
+		resultado := enlaces(t, "?pagina=1&tamano=20")
+		if _, existe := resultado["siguiente"]; !existe {
+			t.Fatalf("la primera página debería enlazar a la siguiente: %v", resultado)
+		}
+		if _, existe := resultado["anterior"]; existe {
+			t.Fatalf("la primera página no debería enlazar a una anterior: %v", resultado)
+		}
+		if !strings.HasPrefix(resultado["siguiente"], "http://api.ejemplo.com/api/v1/usuarios?") {
+			t.Fatalf("el enlace debería ser una URL completa derivada del host: %q", resultado["siguiente"])
+		}
+	})
+
+	t.Run("última página sin siguiente", func(t *testing.T) {
+		resultado := enlaces(t, "?pagina=3&tamano=20")
+		if _, existe := resultado["siguiente"]; existe {
+			t.Fatalf("la última página no debería enlazar a una siguiente: %v", resultado)
+		}
+		if _, existe := resultado["anterior"]; !existe {
+			t.Fatalf("la última página debería enlazar a la anterior: %v", resultado)
+		}
+	})
+
+	t.Run("conserva los parámetros de búsqueda", func(t *testing.T) {
+		resultado := enlaces(t, "?pagina=1&tamano=20&ordenar=nombre")
+		if !strings.Contains(resultado["ultima"], "ordenar=nombre") {
+			t.Fatalf("los enlaces deberían conservar el orden pedido: %q", resultado["ultima"])
+		}
+	})
+}
+
+func TestUsuarioConMetadatos(t *testing.T) {
+	crear := func(t *testing.T, servidor *ServidorHTTP, cuerpo string) *httptest.ResponseRecorder {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", strings.NewReader(cuerpo)))
+		return grabadora
+	}
+
+	t.Run("se crean y se recuperan intactos", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		grabadora := crear(t, servidor, `{"nombre":"Ana","email":"ana@ejemplo.com","metadatos":{"telefono":"555-1234","departamento":"ventas"}}`)
+		if grabadora.Code != http.StatusCreated {
+			t.Fatalf("código esperado 201, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+
+		grabadora = httptest.NewRecorder()
+		servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios/1", nil))
+		var respuesta struct {
+			Datos Usuario `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if respuesta.Datos.Metadatos["telefono"] != "555-1234" || respuesta.Datos.Metadatos["departamento"] != "ventas" {
+			t.Fatalf("los metadatos deberían volver tal cual: %+v", respuesta.Datos.Metadatos)
+		}
+	})
+
+	t.Run("clave vacía da 400", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		grabadora := crear(t, servidor, `{"nombre":"Ana","email":"ana@ejemplo.com","metadatos":{"":"valor"}}`)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("una clave vacía debería dar 400, dio %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), CodigoMetadatosInvalidos) {
+			t.Fatalf("falta el código de error estable: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("límite de claves configurable", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{MaximoClavesMetadatos: 1})
+		grabadora := crear(t, servidor, `{"nombre":"Ana","email":"ana@ejemplo.com","metadatos":{"a":"1","b":"2"}}`)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("exceder el máximo de claves debería dar 400, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("valor demasiado largo da 400", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{LongitudMaximaMetadato: 5})
+		grabadora := crear(t, servidor, `{"nombre":"Ana","email":"ana@ejemplo.com","metadatos":{"nota":"demasiado largo"}}`)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un valor sobre el límite debería dar 400, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("el PATCH reemplaza el mapa completo", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		crear(t, servidor, `{"nombre":"Ana","email":"ana@ejemplo.com","metadatos":{"telefono":"555-1234"}}`)
+
+		grabadora := httptest.NewRecorder()
+		peticion := httptest.NewRequest(http.MethodPatch, "/usuarios/1", strings.NewReader(`{"metadatos":{"departamento":"ventas"}}`))
+		servidor.manejarUsuarioPorID(grabadora, peticion)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		var respuesta struct {
+			Datos Usuario `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if _, existe := respuesta.Datos.Metadatos["telefono"]; existe {
+			t.Fatalf("el patch de metadatos debería reemplazar el mapa: %+v", respuesta.Datos.Metadatos)
+		}
+		if respuesta.Datos.Metadatos["departamento"] != "ventas" {
+			t.Fatalf("falta el metadato nuevo: %+v", respuesta.Datos.Metadatos)
+		}
+	})
+}
+
+func TestOrdenarUsuariosPorParametro(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Carlos", "carlos@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "ana", "ana@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "Beatriz", "bea@ejemplo.com")
+
+	listar := func(t *testing.T, query string) []Usuario {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios"+query, nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		var respuesta respuestaPaginada
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return respuesta.Datos.Usuarios
+	}
+
+	t.Run("ascendente por nombre", func(t *testing.T) {
+		usuarios := listar(t, "?ordenar=nombre")
+		if usuarios[0].Nombre != "ana" || usuarios[1].Nombre != "Beatriz" || usuarios[2].Nombre != "Carlos" {
+			t.Fatalf("orden ascendente inesperado: %+v", usuarios)
+		}
+	})
+
+	t.Run("descendente por nombre", func(t *testing.T) {
+		usuarios := listar(t, "?ordenar=nombre&direccion=desc")
+		if usuarios[0].Nombre != "Carlos" || usuarios[2].Nombre != "ana" {
+			t.Fatalf("orden descendente inesperado: %+v", usuarios)
+		}
+	})
+
+	t.Run("campo inválido da 400 con los permitidos", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios?ordenar=apellido", nil))
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un campo inválido debería dar 400, dio %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), "fecha_creado") {
+			t.Fatalf("el mensaje debería listar los campos permitidos: %s", grabadora.Body.String())
+		}
+	})
+}
+
+func TestRechazaCamposDesconocidos(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	cuerpo := strings.NewReader(`{"nombrre":"Ana","email":"ana@ejemplo.com"}`)
+	grabadora := httptest.NewRecorder()
+	servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+	if grabadora.Code != http.StatusBadRequest {
+		t.Fatalf("un campo desconocido debería dar 400, dio %d", grabadora.Code)
+	}
+	if !strings.Contains(grabadora.Body.String(), `nombrre`) || !strings.Contains(grabadora.Body.String(), "no se reconoce") {
+		t.Fatalf("el mensaje debería señalar el campo desconocido: %s", grabadora.Body.String())
+	}
+
+	usuario := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	cuerpo = strings.NewReader(`{"nombre":"Ana","emial":"ana@ejemplo.com"}`)
+	grabadora = httptest.NewRecorder()
+	servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodPut, fmt.Sprintf("/usuarios/%d", usuario.ID), cuerpo))
+	if grabadora.Code != http.StatusBadRequest {
+		t.Fatalf("el PUT también debería rechazar campos desconocidos, dio %d", grabadora.Code)
+	}
+}
+
+func TestCrearUsuariosEnLote(t *testing.T) {
+	type respuestaLote struct {
+		Datos struct {
+			Creados    int             `json:"creados"`
+			Fallidos   int             `json:"fallidos"`
+			Resultados []resultadoLote `json:"resultados"`
+		} `json:"datos"`
+	}
+
+	enviarLote := func(t *testing.T, servidor *ServidorHTTP, cuerpo string) (*httptest.ResponseRecorder, respuestaLote) {
+		t.Helper()
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuariosLote(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios/lote", strings.NewReader(cuerpo)))
+		var respuesta respuestaLote
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		return grabadora, respuesta
+	}
+
+	t.Run("lote totalmente válido", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		grabadora, respuesta := enviarLote(t, servidor, `[
+			{"nombre":"Ana","email":"ana@ejemplo.com"},
+			{"nombre":"Juan","email":"juan@ejemplo.com"}
+		]`)
+		if grabadora.Code != http.StatusCreated {
+			t.Fatalf("un lote válido debería dar 201, dio %d", grabadora.Code)
+		}
+		if respuesta.Datos.Creados != 2 || respuesta.Datos.Fallidos != 0 {
+			t.Fatalf("resumen inesperado: %+v", respuesta.Datos)
+		}
+		if respuesta.Datos.Resultados[0].ID != 1 || respuesta.Datos.Resultados[1].ID != 2 {
+			t.Fatalf("los IDs deberían ser consecutivos: %+v", respuesta.Datos.Resultados)
+		}
+	})
+
+	t.Run("lote mixto crea los válidos con 207", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		crearUsuarioDePrueba(t, servidor, "Previa", "previa@ejemplo.com")
+
+		grabadora, respuesta := enviarLote(t, servidor, `[
+			{"nombre":"Ana","email":"ana@ejemplo.com"},
+			{"nombre":"","email":"sin-nombre@ejemplo.com"},
+			{"nombre":"Eco","email":"ana@ejemplo.com"},
+			{"nombre":"Dup","email":"previa@ejemplo.com"},
+			{"nombre":"Rota","email":"no-es-email"}
+		]`)
+		if grabadora.Code != http.StatusMultiStatus {
+			t.Fatalf("un lote mixto debería dar 207, dio %d", grabadora.Code)
+		}
+		if respuesta.Datos.Creados != 1 || respuesta.Datos.Fallidos != 4 {
+			t.Fatalf("resumen inesperado: %+v", respuesta.Datos)
+		}
+		for _, resultado := range respuesta.Datos.Resultados[1:] {
+			if resultado.Error == "" {
+				t.Fatalf("el elemento %d debería traer su razón de rechazo", resultado.Indice)
+			}
+		}
+
+		if total := contarEnAlmacen(t, servidor.almacen); total != 2 {
+			t.Fatalf("deberían quedar 2 usuarios (previa + ana), hay %d", total)
+		}
+	})
+
+	t.Run("lote vacío da 400", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		grabadora, _ := enviarLote(t, servidor, `[]`)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un lote vacío debería dar 400, dio %d", grabadora.Code)
+		}
+	})
+}
+
+func TestExtraerIDRuta(t *testing.T) {
+	casos := []struct {
+		ruta     string
+		id       int
+		conError bool
+	}{
+		{"/usuarios/1", 1, false},
+		{"/usuarios/1/", 1, false},
+		{"/usuarios/42", 42, false},
+		{"/usuarios/abc", 0, true},
+		{"/usuarios/1/extra", 0, true},
+		{"/usuarios/", 0, true},
+		{"/usuarios/0", 0, true},
+		{"/usuarios/-3", 0, true},
+	}
+
+	for _, caso := range casos {
+		id, err := extraerIDRuta(caso.ruta, "/usuarios/")
+		if caso.conError {
+			if err == nil {
+				t.Errorf("extraerIDRuta(%q) debería fallar, devolvió %d", caso.ruta, id)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("extraerIDRuta(%q) devolvió error: %v", caso.ruta, err)
+		} else if id != caso.id {
+			t.Errorf("extraerIDRuta(%q) = %d, se esperaba %d", caso.ruta, id, caso.id)
+		}
+	}
+}
+
+func TestUsuarioPorIDConBarraFinal(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	usuario := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	grabadora := httptest.NewRecorder()
+	ruta := fmt.Sprintf("/usuarios/%d/", usuario.ID)
+	servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodGet, ruta, nil))
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("la barra final debería tolerarse, dio %d", grabadora.Code)
+	}
+
+	grabadora = httptest.NewRecorder()
+	servidor.manejarUsuarioPorID(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios/1/extra", nil))
+	if grabadora.Code != http.StatusBadRequest {
+		t.Fatalf("los segmentos adicionales deberían dar 400, dio %d", grabadora.Code)
+	}
+}
+
+func TestRutasVersionadasBajoPrefijo(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	rutas := servidor.configurarRutas()
+
+	t.Run("los recursos responden bajo /api/v1", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		rutas.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/api/v1/usuarios", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+
+		grabadora = httptest.NewRecorder()
+		rutas.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/api/v1/usuarios/1", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("la ruta por ID versionada debería responder 200, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("la salud sigue en la raíz", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		rutas.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/salud", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d", grabadora.Code)
+		}
+	})
+
+	t.Run("la raíz anuncia las rutas versionadas", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		rutas.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/", nil))
+		var respuesta struct {
+			Datos struct {
+				Endpoints []string `json:"endpoints_disponibles"`
+			} `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		anunciadas := strings.Join(respuesta.Datos.Endpoints, " ")
+		if !strings.Contains(anunciadas, "/api/v1/usuarios") || !strings.Contains(anunciadas, "/salud") {
+			t.Fatalf("endpoints anunciados inesperados: %v", respuesta.Datos.Endpoints)
+		}
+	})
+
+	t.Run("el prefijo es configurable", func(t *testing.T) {
+		otro := NuevoServidor(&Configuracion{PrefijoAPI: "/api/v2"})
+		grabadora := httptest.NewRecorder()
+		otro.configurarRutas().ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/api/v2/usuarios", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("el prefijo configurado debería atender, dio %d", grabadora.Code)
+		}
+	})
+}
+
+func TestManejadorMontableConServidorDePruebas(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	pruebas := httptest.NewServer(servidor.Manejador())
+	defer pruebas.Close()
+
+	respuesta, err := http.Get(pruebas.URL + "/salud")
+	if err != nil {
+		t.Fatalf("la petición a /salud falló: %v", err)
+	}
+	defer respuesta.Body.Close()
+
+	if respuesta.StatusCode != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", respuesta.StatusCode)
+	}
+	if id := respuesta.Header.Get(cabeceraIDCorrelacion); id == "" {
+		t.Fatal("la cadena de middlewares debería estar aplicada (falta X-Request-ID)")
+	}
+}
+
+func TestDetenerApagaElServidorSinError(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{DireccionServidor: "127.0.0.1", Puerto: 0, TiempoEspera: Duracion(5 * time.Second)})
+
+	errores := make(chan error, 1)
+	go func() { errores <- servidor.IniciarServidor() }()
+	esperarCondicion(t, time.Second, func() bool { return servidor.servidorWeb != nil })
+
+	ctx, cancelar := context.WithTimeout(context.Background(), time.Second)
+	defer cancelar()
+	if err := servidor.Detener(ctx); err != nil {
+		t.Fatalf("Detener devolvió error: %v", err)
+	}
+
+	select {
+	case err := <-errores:
+		if !errors.Is(err, http.ErrServerClosed) {
+			t.Fatalf("IniciarServidor debería retornar ErrServerClosed, retornó %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("IniciarServidor no retornó tras Detener")
+	}
+}
+
+// generarCertificadoDePrueba escribe un certificado autofirmado y su clave
+// en el directorio temporal del test y devuelve ambas rutas.
+func generarCertificadoDePrueba(t *testing.T) (string, string) {
+	t.Helper()
+
+	clave, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plantilla := x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "localhost"},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		DNSNames:     []string{"localhost"},
+	}
+	certificado, err := x509.CreateCertificate(rand.Reader, &plantilla, &plantilla, &clave.PublicKey, clave)
+	if err != nil {
+		t.Fatal(err)
+	}
+	claveDER, err := x509.MarshalECPrivateKey(clave)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	directorio := t.TempDir()
+	rutaCert := filepath.Join(directorio, "cert.pem")
+	rutaClave := filepath.Join(directorio, "clave.pem")
+	if err := os.WriteFile(rutaCert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certificado}), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(rutaClave, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: claveDER}), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return rutaCert, rutaClave
+}
+
+func TestValidarTLS(t *testing.T) {
+	rutaCert, rutaClave := generarCertificadoDePrueba(t)
+
+	if usar, err := (&Configuracion{}).validarTLS(); err != nil || usar {
+		t.Fatalf("sin rutas debería ser HTTP plano, dio (%v, %v)", usar, err)
+	}
+	if usar, err := (&Configuracion{CertificadoTLS: rutaCert, ClaveTLS: rutaClave}).validarTLS(); err != nil || !usar {
+		t.Fatalf("con ambas rutas debería activarse TLS, dio (%v, %v)", usar, err)
+	}
+	if _, err := (&Configuracion{CertificadoTLS: rutaCert}).validarTLS(); err == nil {
+		t.Fatal("con solo el certificado debería fallar")
+	}
+	if _, err := (&Configuracion{CertificadoTLS: rutaCert, ClaveTLS: "/no/existe.pem"}).validarTLS(); err == nil {
+		t.Fatal("con una ruta inexistente debería fallar")
+	}
+}
+
+func TestIniciarServidorConTLS(t *testing.T) {
+	rutaCert, rutaClave := generarCertificadoDePrueba(t)
+	servidor := NuevoServidor(&Configuracion{
+		DireccionServidor: "127.0.0.1",
+		Puerto:            0,
+		TiempoEspera:      Duracion(5 * time.Second),
+		CertificadoTLS:    rutaCert,
+		ClaveTLS:          rutaClave,
+	})
+
+	errores := make(chan error, 1)
+	go func() { errores <- servidor.IniciarServidor() }()
+	esperarCondicion(t, time.Second, func() bool { return servidor.servidorWeb != nil })
+
+	select {
+	case err := <-errores:
+		t.Fatalf("el arranque TLS falló de inmediato: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+
+	ctx, cancelar := context.WithTimeout(context.Background(), time.Second)
+	defer cancelar()
+	if err := servidor.Detener(ctx); err != nil {
+		t.Fatalf("Detener devolvió error: %v", err)
+	}
+}
+
+// esperarCondicion sondea la condición hasta que se cumpla o venza el plazo.
+func esperarCondicion(t *testing.T, plazo time.Duration, condicion func() bool) {
+	t.Helper()
+	limite := time.Now().Add(plazo)
+	for time.Now().Before(limite) {
+		if condicion() {
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatal("la condición no se cumplió dentro del plazo")
+}
+
+func TestSeleccionDeCamposEnLaLista(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	if err := servidor.almacen.Reemplazar(usuariosDePrueba(3)); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Run("devuelve solo los campos pedidos", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios?campos=id,nombre", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+
+		var respuesta struct {
+			Datos struct {
+				Usuarios []map[string]interface{} `json:"usuarios"`
+			} `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if len(respuesta.Datos.Usuarios) != 3 {
+			t.Fatalf("se esperaban 3 usuarios, hay %d", len(respuesta.Datos.Usuarios))
+		}
+		for _, usuario := range respuesta.Datos.Usuarios {
+			if len(usuario) != 2 {
+				t.Fatalf("cada usuario debería traer solo id y nombre: %v", usuario)
+			}
+			if _, existe := usuario["id"]; !existe {
+				t.Fatalf("falta el campo id: %v", usuario)
+			}
+			if _, existe := usuario["email"]; existe {
+				t.Fatalf("el email no se pidió y no debería venir: %v", usuario)
+			}
+		}
+	})
+
+	t.Run("se combina con la paginación", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios?campos=email&pagina=2&tamano=1", nil))
+
+		var respuesta struct {
+			Datos struct {
+				Usuarios   []map[string]interface{} `json:"usuarios"`
+				Paginacion struct {
+					Pagina int `json:"pagina"`
+				} `json:"paginacion"`
+			} `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if len(respuesta.Datos.Usuarios) != 1 || respuesta.Datos.Paginacion.Pagina != 2 {
+			t.Fatalf("se esperaba la segunda página con un usuario: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("un campo inexistente da 400", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.obtenerUsuarios(grabadora, httptest.NewRequest(http.MethodGet, "/usuarios?campos=id,apellido", nil))
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("código esperado 400, obtenido %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), "apellido") || !strings.Contains(grabadora.Body.String(), CodigoParametroInvalido) {
+			t.Fatalf("el error debería nombrar el campo desconocido: %s", grabadora.Body.String())
+		}
+	})
+}
+
+func TestValidarConfiguracion(t *testing.T) {
+	valida := Configuracion{
+		DireccionServidor: "0.0.0.0",
+		Puerto:            8080,
+		TiempoEspera:      Duracion(30 * time.Second),
+	}
+
+	casos := []struct {
+		nombre   string
+		modifica func(*Configuracion)
+		espera   []string
+	}{
+		{
+			nombre:   "configuración válida",
+			modifica: func(c *Configuracion) {},
+		},
+		{
+			nombre:   "puerto fuera de rango",
+			modifica: func(c *Configuracion) { c.Puerto = 70000 },
+			espera:   []string{"70000", "fuera de rango"},
+		},
+		{
+			nombre:   "dirección vacía",
+			modifica: func(c *Configuracion) { c.DireccionServidor = "" },
+			espera:   []string{"dirección"},
+		},
+		{
+			nombre:   "tiempo de espera no positivo",
+			modifica: func(c *Configuracion) { c.TiempoEspera = 0 },
+			espera:   []string{"tiempo de espera"},
+		},
+		{
+			nombre:   "timeout negativo",
+			modifica: func(c *Configuracion) { c.TiempoInactividad = Duracion(-time.Second) },
+			espera:   []string{"tiempo_inactividad", "negativo"},
+		},
+		{
+			nombre: "varios problemas se agregan en un solo error",
+			modifica: func(c *Configuracion) {
+				c.Puerto = -1
+				c.DireccionServidor = ""
+				c.TiempoLectura = Duracion(-time.Second)
+			},
+			espera: []string{"fuera de rango", "dirección", "tiempo_lectura"},
+		},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			config := valida
+			caso.modifica(&config)
+			err := config.Validar()
+			if len(caso.espera) == 0 {
+				if err != nil {
+					t.Fatalf("Validar devolvió error para una configuración válida: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("Validar debería devolver error")
+			}
+			for _, fragmento := range caso.espera {
+				if !strings.Contains(err.Error(), fragmento) {
+					t.Fatalf("el error debería mencionar %q: %v", fragmento, err)
+				}
+			}
+		})
+	}
+}
+
+func TestIniciarServidorRechazaConfiguracionInvalida(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{DireccionServidor: "127.0.0.1", Puerto: 70000, TiempoEspera: Duracion(time.Second)})
+	if err := servidor.IniciarServidor(); err == nil || !strings.Contains(err.Error(), "configuración inválida") {
+		t.Fatalf("IniciarServidor debería fallar rápido con la causa: %v", err)
+	}
+}
+
+func TestPurgarUsuarios(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "Benito", "benito@ejemplo.com")
+
+	t.Run("sin confirmación responde 400 y no borra nada", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.manejarPurgarUsuarios(grabadora, httptest.NewRequest(http.MethodDelete, "/admin/usuarios", nil))
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("sin confirmar=si se esperaba 400, obtenido %d", grabadora.Code)
+		}
+		if total := contarEnAlmacen(t, servidor.almacen); total != 2 {
+			t.Fatalf("los usuarios no deberían tocarse: quedan %d", total)
+		}
+	})
+
+	t.Run("con confirmación vacía la lista y reinicia los IDs", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.manejarPurgarUsuarios(grabadora, httptest.NewRequest(http.MethodDelete, "/admin/usuarios?confirmar=si", nil))
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		if !strings.Contains(grabadora.Body.String(), `"eliminados":2`) {
+			t.Fatalf("la respuesta debería reportar los eliminados: %s", grabadora.Body.String())
+		}
+		if total := contarEnAlmacen(t, servidor.almacen); total != 0 {
+			t.Fatalf("la lista debería quedar vacía, quedan %d", total)
+		}
+
+		// El contador vuelve a empezar: el siguiente usuario recibe el ID 1.
+		nuevo := crearUsuarioDePrueba(t, servidor, "Carla", "carla@ejemplo.com")
+		if nuevo.ID != 1 {
+			t.Fatalf("tras la purga el primer ID debería ser 1, fue %d", nuevo.ID)
+		}
+	})
+
+	t.Run("solo admite DELETE", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.manejarPurgarUsuarios(grabadora, httptest.NewRequest(http.MethodPost, "/admin/usuarios?confirmar=si", nil))
+		if grabadora.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("con POST se esperaba 405, obtenido %d", grabadora.Code)
+		}
+	})
+}
+
+func TestMaximoDeUsuariosConfigurado(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{MaximoUsuarios: 2})
+	crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+	crearUsuarioDePrueba(t, servidor, "Benito", "benito@ejemplo.com")
+
+	t.Run("al alcanzar el límite el siguiente se rechaza con 507", func(t *testing.T) {
+		cuerpo := strings.NewReader(`{"nombre":"Carla","email":"carla@ejemplo.com"}`)
+		grabadora := httptest.NewRecorder()
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+
+		if grabadora.Code != http.StatusInsufficientStorage {
+			t.Fatalf("código esperado 507, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+		if !strings.Contains(grabadora.Body.String(), CodigoLimiteUsuarios) {
+			t.Fatalf("falta el código de error: %s", grabadora.Body.String())
+		}
+		if total := contarEnAlmacen(t, servidor.almacen); total != 2 {
+			t.Fatalf("el límite no debería superarse: hay %d usuarios", total)
+		}
+	})
+
+	t.Run("el lote también respeta el límite", func(t *testing.T) {
+		cuerpo := strings.NewReader(`[{"nombre":"Carla","email":"carla@ejemplo.com"}]`)
+		peticion := httptest.NewRequest(http.MethodPost, "/usuarios/lote", cuerpo)
+		peticion.Header.Set("Content-Type", "application/json")
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuariosLote(grabadora, peticion)
+		if grabadora.Code != http.StatusInsufficientStorage {
+			t.Fatalf("código esperado 507, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+		}
+	})
+
+	t.Run("tras eliminar uno vuelve a haber sitio", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		servidor.eliminarUsuario(grabadora, httptest.NewRequest(http.MethodDelete, "/usuarios/1", nil), 1)
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("la eliminación falló: %d", grabadora.Code)
+		}
+		crearUsuarioDePrueba(t, servidor, "Carla", "carla@ejemplo.com")
+	})
+
+	t.Run("un lote que desborda a mitad reporta el resto por fila", func(t *testing.T) {
+		cuerpo := strings.NewReader(`[{"nombre":"Diego","email":"diego@ejemplo.com"}]`)
+		peticion := httptest.NewRequest(http.MethodPost, "/usuarios/lote", cuerpo)
+		peticion.Header.Set("Content-Type", "application/json")
+		grabadora := httptest.NewRecorder()
+		servidor.manejarUsuariosLote(grabadora, peticion)
+		if grabadora.Code != http.StatusInsufficientStorage {
+			t.Fatalf("con el almacén lleno se esperaba 507, obtenido %d", grabadora.Code)
+		}
+	})
+}
+
+func TestSinMaximoDeUsuariosNoHayLimite(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	for i := 0; i < 25; i++ {
+		crearUsuarioDePrueba(t, servidor, fmt.Sprintf("Usuario%d", i), fmt.Sprintf("usuario%d@ejemplo.com", i))
+	}
+	if total := contarEnAlmacen(t, servidor.almacen); total != 25 {
+		t.Fatalf("sin límite deberían existir 25 usuarios, hay %d", total)
+	}
+}
+
+func TestMensajesDeErrorDeJSON(t *testing.T) {
+	casos := []struct {
+		nombre string
+		cuerpo string
+		espera string
+	}{
+		{
+			nombre: "cuerpo vacío",
+			cuerpo: "",
+			espera: "El cuerpo de la petición no puede estar vacío",
+		},
+		{
+			nombre: "JSON truncado",
+			cuerpo: `{"nombre":"Ana","email":`,
+			espera: "El JSON está incompleto",
+		},
+		{
+			nombre: "JSON malformado indica la posición",
+			cuerpo: `{"nombre": Ana}`,
+			espera: "JSON malformado en la posición 12",
+		},
+		{
+			nombre: "tipo incorrecto nombra el campo y el tipo esperado",
+			cuerpo: `{"nombre": 42}`,
+			espera: "El campo nombre debe ser string",
+		},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			servidor := NuevoServidor(&Configuracion{})
+			grabadora := httptest.NewRecorder()
+			servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", strings.NewReader(caso.cuerpo)))
+			if grabadora.Code != http.StatusBadRequest {
+				t.Fatalf("se esperaba 400, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+			}
+			if !strings.Contains(grabadora.Body.String(), caso.espera) {
+				t.Fatalf("falta el mensaje %q en: %s", caso.espera, grabadora.Body.String())
+			}
+			if !strings.Contains(grabadora.Body.String(), CodigoCuerpoInvalido) {
+				t.Fatalf("falta el código de error estable: %s", grabadora.Body.String())
+			}
+		})
+	}
+}
+
+func TestIniciarServidorEnSocketUnix(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "api.sock")
+	servidor := NuevoServidor(&Configuracion{
+		DireccionServidor: "unix:" + ruta,
+		TiempoEspera:      Duracion(5 * time.Second),
+	})
+
+	errores := make(chan error, 1)
+	go func() { errores <- servidor.IniciarServidor() }()
+	esperarCondicion(t, time.Second, func() bool {
+		_, err := os.Stat(ruta)
+		return err == nil
+	})
+	defer func() {
+		ctx, cancelar := context.WithTimeout(context.Background(), time.Second)
+		defer cancelar()
+		if err := servidor.Detener(ctx); err != nil {
+			t.Errorf("Detener devolvió error: %v", err)
+		}
+	}()
+
+	cliente := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, red, direccion string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", ruta)
+		},
+	}}
+	respuesta, err := cliente.Get("http://servidor-local/salud")
+	if err != nil {
+		t.Fatalf("la petición por el socket falló: %v", err)
+	}
+	defer respuesta.Body.Close()
+	if respuesta.StatusCode != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", respuesta.StatusCode)
+	}
+
+	select {
+	case err := <-errores:
+		t.Fatalf("el servidor terminó antes de tiempo: %v", err)
+	default:
+	}
+}
+
+func TestEscucharSocketUnixRetiraElHuerfano(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "huerfano.sock")
+
+	previo, err := net.Listen("unix", ruta)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Cerrar sin Remove simula un proceso anterior que murió sin limpiar;
+	// el archivo del socket queda en disco.
+	previo.(*net.UnixListener).SetUnlinkOnClose(false)
+	previo.Close()
+
+	oyente, err := escucharSocketUnix(ruta)
+	if err != nil {
+		t.Fatalf("debería reemplazar el socket huérfano: %v", err)
+	}
+	oyente.Close()
+}
+
+func TestRegistrarRutaPersonalizada(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	err := servidor.RegistrarRuta("/personalizado", func(w http.ResponseWriter, r *http.Request) {
+		enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{Exitoso: true, Mensaje: "ruta externa"})
+	})
+	if err != nil {
+		t.Fatalf("RegistrarRuta devolvió error: %v", err)
+	}
+
+	pruebas := httptest.NewServer(servidor.Manejador())
+	defer pruebas.Close()
+
+	respuesta, err := http.Get(pruebas.URL + "/personalizado")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer respuesta.Body.Close()
+	if respuesta.StatusCode != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d", respuesta.StatusCode)
+	}
+	// La cadena de middlewares aplica igual que a las rutas internas.
+	if respuesta.Header.Get(cabeceraIDCorrelacion) == "" {
+		t.Fatal("la ruta personalizada debería pasar por los middlewares")
+	}
+
+	var cuerpo RespuestaJSON
+	if err := json.NewDecoder(respuesta.Body).Decode(&cuerpo); err != nil {
+		t.Fatal(err)
+	}
+	if cuerpo.Mensaje != "ruta externa" {
+		t.Fatalf("respuesta inesperada: %+v", cuerpo)
+	}
+}
+
+func TestRegistrarRutaRechazaColisiones(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	casos := []struct {
+		nombre string
+		patron string
+	}{
+		{nombre: "ruta interna", patron: "/usuarios"},
+		{nombre: "ruta operativa", patron: "/salud"},
+		{nombre: "bajo el prefijo de versionado", patron: "/api/v1/propio"},
+		{nombre: "sin barra inicial", patron: "propio"},
+	}
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			if err := servidor.RegistrarRuta(caso.patron, func(w http.ResponseWriter, r *http.Request) {}); err == nil {
+				t.Fatalf("el patrón %q debería rechazarse", caso.patron)
+			}
+		})
+	}
+
+	t.Run("duplicado entre rutas personalizadas", func(t *testing.T) {
+		if err := servidor.RegistrarRuta("/propio", func(w http.ResponseWriter, r *http.Request) {}); err != nil {
+			t.Fatalf("el primer registro debería aceptarse: %v", err)
+		}
+		if err := servidor.RegistrarRuta("/propio", func(w http.ResponseWriter, r *http.Request) {}); err == nil {
+			t.Fatal("el segundo registro del mismo patrón debería rechazarse")
+		}
+	})
+}
+
+func TestCrearUsuarioAnunciaLocation(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	pruebas := httptest.NewServer(servidor.Manejador())
+	defer pruebas.Close()
+
+	respuesta, err := http.Post(pruebas.URL+"/api/v1/usuarios", "application/json",
+		strings.NewReader(`{"nombre":"Ana","email":"ana@ejemplo.com"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer respuesta.Body.Close()
+	if respuesta.StatusCode != http.StatusCreated {
+		t.Fatalf("código esperado 201, obtenido %d", respuesta.StatusCode)
+	}
+	if location := respuesta.Header.Get("Location"); location != "/api/v1/usuarios/1" {
+		t.Fatalf("Location esperado /api/v1/usuarios/1, obtenido %q", location)
+	}
+
+	// El Location debe ser navegable tal cual.
+	seguimiento, err := http.Get(pruebas.URL + respuesta.Header.Get("Location"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer seguimiento.Body.Close()
+	if seguimiento.StatusCode != http.StatusOK {
+		t.Fatalf("el recurso anunciado debería existir, respondió %d", seguimiento.StatusCode)
+	}
+}
+
+// puertoLibre pide al sistema un puerto TCP libre y lo devuelve cerrado,
+// para configurar listeners que no admiten puerto cero.
+func puertoLibre(t *testing.T) int {
+	t.Helper()
+	oyente, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer oyente.Close()
+	return oyente.Addr().(*net.TCPAddr).Port
+}
+
+func TestListenersHTTPyHTTPSCoordinados(t *testing.T) {
+	rutaCert, rutaClave := generarCertificadoDePrueba(t)
+	puertoHTTP := puertoLibre(t)
+	servidor := NuevoServidor(&Configuracion{
+		DireccionServidor:     "127.0.0.1",
+		Puerto:                0,
+		PuertoRedireccionHTTP: puertoHTTP,
+		TiempoEspera:          Duracion(5 * time.Second),
+		CertificadoTLS:        rutaCert,
+		ClaveTLS:              rutaClave,
+	})
+
+	errores := make(chan error, 1)
+	go func() { errores <- servidor.IniciarServidor() }()
+	esperarCondicion(t, time.Second, func() bool { return servidor.servidorRedireccion != nil })
+
+	// El listener plano solo redirige con 301 hacia HTTPS.
+	sinSeguir := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	}}
+	var respuesta *http.Response
+	esperarCondicion(t, time.Second, func() bool {
+		var err error
+		respuesta, err = sinSeguir.Get(fmt.Sprintf("http://127.0.0.1:%d/salud", puertoHTTP))
+		return err == nil
+	})
+	defer respuesta.Body.Close()
+	if respuesta.StatusCode != http.StatusMovedPermanently {
+		t.Fatalf("se esperaba 301, obtenido %d", respuesta.StatusCode)
+	}
+	if destino := respuesta.Header.Get("Location"); !strings.HasPrefix(destino, "https://127.0.0.1") || !strings.HasSuffix(destino, "/salud") {
+		t.Fatalf("Location inesperado: %q", destino)
+	}
+
+	// La caída de un listener arrastra al otro y IniciarServidor retorna.
+	servidor.servidorRedireccion.Close()
+	select {
+	case <-errores:
+	case <-time.After(2 * time.Second):
+		t.Fatal("cerrar un listener debería terminar IniciarServidor")
+	}
+	if _, err := sinSeguir.Get(fmt.Sprintf("http://127.0.0.1:%d/salud", puertoHTTP)); err == nil {
+		t.Fatal("el puerto de redirección debería estar cerrado")
+	}
+}
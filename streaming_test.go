@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func usuariosDePrueba(n int) []Usuario {
+	usuarios := make([]Usuario, n)
+	for i := range usuarios {
+		usuarios[i] = Usuario{
+			ID:     i + 1,
+			Nombre: fmt.Sprintf("Usuario %d", i+1),
+			Email:  fmt.Sprintf("usuario%d@ejemplo.com", i+1),
+			Creado: "2024-01-01 00:00:00",
+		}
+	}
+	return usuarios
+}
+
+func TestEscribirUsuariosEnStreamingProduceJSONValido(t *testing.T) {
+	grabadora := httptest.NewRecorder()
+	escribirUsuariosEnStreaming(grabadora, usuariosDePrueba(5))
+
+	var respuesta struct {
+		Exitoso bool      `json:"exitoso"`
+		Mensaje string    `json:"mensaje"`
+		Datos   []Usuario `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatalf("la respuesta en streaming no es JSON válido: %v", err)
+	}
+	if !respuesta.Exitoso || len(respuesta.Datos) != 5 {
+		t.Fatalf("respuesta inesperada: exitoso=%v, %d usuarios", respuesta.Exitoso, len(respuesta.Datos))
+	}
+	if respuesta.Datos[2].Nombre != "Usuario 3" {
+		t.Fatalf("usuario fuera de orden: %+v", respuesta.Datos[2])
+	}
+}
+
+func TestEscribirUsuariosEnStreamingListaVacia(t *testing.T) {
+	grabadora := httptest.NewRecorder()
+	escribirUsuariosEnStreaming(grabadora, nil)
+
+	var respuesta struct {
+		Datos []Usuario `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatalf("la respuesta vacía no es JSON válido: %v", err)
+	}
+	if len(respuesta.Datos) != 0 {
+		t.Fatalf("se esperaba lista vacía, hay %d", len(respuesta.Datos))
+	}
+}
+
+// escritorDescarte implementa http.ResponseWriter tirando el cuerpo, para
+// medir solo el coste de serialización.
+type escritorDescarte struct{ cabeceras http.Header }
+
+func (e *escritorDescarte) Header() http.Header         { return e.cabeceras }
+func (e *escritorDescarte) WriteHeader(int)             {}
+func (e *escritorDescarte) Write(p []byte) (int, error) { return len(p), nil }
+
+func BenchmarkSerializacionCompleta(b *testing.B) {
+	usuarios := usuariosDePrueba(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		enviarRespuestaJSON(&escritorDescarte{cabeceras: http.Header{}}, http.StatusOK, RespuestaJSON{
+			Exitoso: true,
+			Mensaje: fmt.Sprintf("%d usuarios encontrados", len(usuarios)),
+			Datos:   usuarios,
+		})
+	}
+}
+
+func BenchmarkSerializacionStreaming(b *testing.B) {
+	usuarios := usuariosDePrueba(10000)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		escribirUsuariosEnStreaming(&escritorDescarte{cabeceras: http.Header{}}, usuarios)
+	}
+}
+
+func TestGetUsuariosConAcceptNDJSON(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	if err := servidor.almacen.Reemplazar(usuariosDePrueba(5)); err != nil {
+		t.Fatal(err)
+	}
+	manejador := servidor.construirManejador()
+
+	peticion := httptest.NewRequest(http.MethodGet, "/api/v1/usuarios", nil)
+	peticion.Header.Set("Accept", "application/x-ndjson")
+	grabadora := httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, peticion)
+
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+	}
+	if tipo := grabadora.Header().Get("Content-Type"); tipo != "application/x-ndjson" {
+		t.Fatalf("Content-Type esperado application/x-ndjson, fue %q", tipo)
+	}
+
+	// Cada línea es un usuario JSON completo; no hay envoltura ni array.
+	lector := bufio.NewScanner(grabadora.Body)
+	var leidos []Usuario
+	for lector.Scan() {
+		var usuario Usuario
+		if err := json.Unmarshal(lector.Bytes(), &usuario); err != nil {
+			t.Fatalf("línea NDJSON inválida %q: %v", lector.Text(), err)
+		}
+		leidos = append(leidos, usuario)
+	}
+	if err := lector.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if len(leidos) != 5 {
+		t.Fatalf("se esperaban 5 líneas, hay %d", len(leidos))
+	}
+	if leidos[4].ID != 5 || leidos[0].Email != "usuario1@ejemplo.com" {
+		t.Fatalf("usuarios fuera de orden: %+v", leidos)
+	}
+}
+
+func TestNDJSONRespetaLosFiltros(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	if err := servidor.almacen.Reemplazar(usuariosDePrueba(9)); err != nil {
+		t.Fatal(err)
+	}
+
+	// El filtro ?buscar= se aplica también al modo streaming y la
+	// paginación se ignora: la exportación siempre es completa.
+	peticion := httptest.NewRequest(http.MethodGet, "/usuarios?buscar=usuario1&pagina=3&tamano=2", nil)
+	peticion.Header.Set("Accept", "application/x-ndjson")
+	grabadora := httptest.NewRecorder()
+	servidor.obtenerUsuarios(grabadora, peticion)
+
+	lineas := 0
+	lector := bufio.NewScanner(grabadora.Body)
+	for lector.Scan() {
+		lineas++
+	}
+	if lineas != 1 {
+		t.Fatalf("solo usuario1 coincide con el filtro, llegaron %d líneas", lineas)
+	}
+}
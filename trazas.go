@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ContextoTraza sitúa una petición dentro de una traza distribuida,
+// siguiendo el formato del header traceparent de W3C Trace Context.
+type ContextoTraza struct {
+	IDTraza    string // 16 bytes en hexadecimal
+	IDSpan     string // 8 bytes en hexadecimal
+	Muestreada bool
+}
+
+// Traceparent serializa el contexto en el formato del header homónimo,
+// listo para propagarse a servicios aguas abajo.
+func (t ContextoTraza) Traceparent() string {
+	banderas := "00"
+	if t.Muestreada {
+		banderas = "01"
+	}
+	return "00-" + t.IDTraza + "-" + t.IDSpan + "-" + banderas
+}
+
+// SpanDeTraza es el registro de un span ya terminado: qué petición fue,
+// de qué traza cuelga y cuánto tardó.
+type SpanDeTraza struct {
+	Nombre    string
+	Traza     ContextoTraza
+	IDPadre   string // IDSpan del traceparent entrante; vacío si la traza nació aquí
+	Inicio    time.Time
+	Duracion  time.Duration
+	Atributos map[string]interface{}
+}
+
+// ProveedorDeTrazas recibe los spans terminados. Hace el papel del
+// TracerProvider de OpenTelemetry reducido a lo que este servidor emite;
+// un exportador real se adapta detrás de esta interfaz, y en pruebas
+// basta con uno que acumule los spans en memoria.
+type ProveedorDeTrazas interface {
+	RegistrarSpan(span SpanDeTraza)
+}
+
+// proveedorDeTrazasEnLog es el proveedor por defecto: vuelca cada span
+// al log estructurado en nivel debug, suficiente para depurar sin
+// infraestructura de tracing.
+type proveedorDeTrazasEnLog struct {
+	servidor *ServidorHTTP
+}
+
+func (p proveedorDeTrazasEnLog) RegistrarSpan(span SpanDeTraza) {
+	p.servidor.registrador.Debug("span terminado",
+		"traza", span.Traza.IDTraza,
+		"span", span.Traza.IDSpan,
+		"padre", span.IDPadre,
+		"nombre", span.Nombre,
+		"duracion", span.Duracion,
+	)
+}
+
+// interpretarTraceparent valida y descompone un header traceparent
+// versión 00. Devuelve false ante cualquier malformación, incluidos los
+// IDs todo ceros que el estándar declara inválidos.
+func interpretarTraceparent(valor string) (ContextoTraza, bool) {
+	partes := strings.Split(strings.TrimSpace(valor), "-")
+	if len(partes) != 4 || partes[0] != "00" || len(partes[1]) != 32 || len(partes[2]) != 16 || len(partes[3]) != 2 {
+		return ContextoTraza{}, false
+	}
+	for _, parte := range partes[1:] {
+		if _, err := hex.DecodeString(parte); err != nil {
+			return ContextoTraza{}, false
+		}
+	}
+	if partes[1] == strings.Repeat("0", 32) || partes[2] == strings.Repeat("0", 16) {
+		return ContextoTraza{}, false
+	}
+	banderas, _ := hex.DecodeString(partes[3])
+	return ContextoTraza{
+		IDTraza:    strings.ToLower(partes[1]),
+		IDSpan:     strings.ToLower(partes[2]),
+		Muestreada: banderas[0]&0x01 != 0,
+	}, true
+}
+
+// idHexAleatorio genera n bytes aleatorios en hexadecimal para los IDs
+// de traza y de span.
+func idHexAleatorio(n int) string {
+	datos := make([]byte, n)
+	// rand.Read sobre el lector del sistema no falla en la práctica; si
+	// lo hiciera, EncodeToString de ceros produce un ID inválido que los
+	// colectores descartan, preferible a tumbar la petición.
+	_, _ = rand.Read(datos)
+	return hex.EncodeToString(datos)
+}
+
+const claveTraza claveContexto = "traza"
+
+// TrazaDeContexto devuelve el contexto de traza de la petición, si
+// MiddlewareTrazas está en la cadena.
+func TrazaDeContexto(ctx context.Context) (ContextoTraza, bool) {
+	traza, ok := ctx.Value(claveTraza).(ContextoTraza)
+	return traza, ok
+}
+
+// ConProveedorDeTrazas sustituye el proveedor por defecto (el que solo
+// escribe en el log) por uno propio: un exportador real en producción o
+// uno que acumule spans en pruebas.
+func (s *ServidorHTTP) ConProveedorDeTrazas(proveedor ProveedorDeTrazas) *ServidorHTTP {
+	if proveedor != nil {
+		s.trazas = proveedor
+	}
+	return s
+}
+
+// MiddlewareTrazas abre un span por petición: continúa la traza del
+// traceparent entrante si es válido o estrena una si no, deja el
+// contexto resultante disponible vía TrazaDeContexto, y devuelve el
+// traceparent propio en la respuesta para que el cliente correlacione.
+// Al terminar entrega el span al ProveedorDeTrazas con método, ruta y
+// código de estado como atributos.
+func (s *ServidorHTTP) MiddlewareTrazas(siguiente http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		padre, heredada := interpretarTraceparent(r.Header.Get("traceparent"))
+		traza := ContextoTraza{IDSpan: idHexAleatorio(8), Muestreada: true}
+		idPadre := ""
+		if heredada {
+			traza.IDTraza = padre.IDTraza
+			traza.Muestreada = padre.Muestreada
+			idPadre = padre.IDSpan
+		} else {
+			traza.IDTraza = idHexAleatorio(16)
+		}
+
+		w.Header().Set("traceparent", traza.Traceparent())
+
+		inicio := time.Now()
+		escritor := &escritorConEstado{ResponseWriter: w, codigo: http.StatusOK}
+		ctx := context.WithValue(r.Context(), claveTraza, traza)
+		siguiente.ServeHTTP(escritor, r.WithContext(ctx))
+
+		s.trazas.RegistrarSpan(SpanDeTraza{
+			Nombre:   r.Method + " " + r.URL.Path,
+			Traza:    traza,
+			IDPadre:  idPadre,
+			Inicio:   inicio,
+			Duracion: time.Since(inicio),
+			Atributos: map[string]interface{}{
+				"http.metodo": r.Method,
+				"http.ruta":   r.URL.Path,
+				"http.estado": escritor.codigo,
+			},
+		})
+	})
+}
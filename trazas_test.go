@@ -0,0 +1,93 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// proveedorDeTrazasDePrueba acumula los spans recibidos para que las
+// pruebas los inspeccionen.
+type proveedorDeTrazasDePrueba struct {
+	spans []SpanDeTraza
+}
+
+func (p *proveedorDeTrazasDePrueba) RegistrarSpan(span SpanDeTraza) {
+	p.spans = append(p.spans, span)
+}
+
+func TestMiddlewareTrazasHeredaElTraceIDEntrante(t *testing.T) {
+	captura := &proveedorDeTrazasDePrueba{}
+	servidor := NuevoServidor(&Configuracion{}).ConProveedorDeTrazas(captura)
+
+	var enContexto ContextoTraza
+	manejador := servidor.MiddlewareTrazas(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enContexto, _ = TrazaDeContexto(r.Context())
+		w.WriteHeader(http.StatusCreated)
+	}))
+
+	peticion := httptest.NewRequest(http.MethodPost, "/usuarios", nil)
+	peticion.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	grabadora := httptest.NewRecorder()
+	manejador.ServeHTTP(grabadora, peticion)
+
+	if len(captura.spans) != 1 {
+		t.Fatalf("se esperaba exactamente un span, hay %d", len(captura.spans))
+	}
+	span := captura.spans[0]
+	if span.Traza.IDTraza != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("el span hijo debería heredar el trace ID: %q", span.Traza.IDTraza)
+	}
+	if span.IDPadre != "00f067aa0ba902b7" {
+		t.Fatalf("el padre debería ser el span entrante: %q", span.IDPadre)
+	}
+	if len(span.Traza.IDSpan) != 16 || span.Traza.IDSpan == span.IDPadre {
+		t.Fatalf("el span hijo necesita un ID propio de 16 hex: %q", span.Traza.IDSpan)
+	}
+	if span.Atributos["http.estado"] != http.StatusCreated || span.Atributos["http.metodo"] != http.MethodPost {
+		t.Fatalf("atributos inesperados: %v", span.Atributos)
+	}
+	if enContexto.IDTraza != span.Traza.IDTraza {
+		t.Fatalf("el handler debería ver la misma traza en su contexto: %+v", enContexto)
+	}
+	if propagado := grabadora.Header().Get("traceparent"); propagado != span.Traza.Traceparent() {
+		t.Fatalf("la respuesta debería anunciar el traceparent propio: %q", propagado)
+	}
+}
+
+func TestMiddlewareTrazasAbreTrazaNueva(t *testing.T) {
+	casos := []struct {
+		nombre      string
+		traceparent string
+	}{
+		{nombre: "sin header"},
+		{nombre: "versión desconocida", traceparent: "ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01"},
+		{nombre: "trace ID todo ceros", traceparent: "00-00000000000000000000000000000000-00f067aa0ba902b7-01"},
+		{nombre: "hex inválido", traceparent: "00-zzzzzzzzzzzzzzzzzzzzzzzzzzzzzzzz-00f067aa0ba902b7-01"},
+	}
+
+	for _, caso := range casos {
+		t.Run(caso.nombre, func(t *testing.T) {
+			captura := &proveedorDeTrazasDePrueba{}
+			servidor := NuevoServidor(&Configuracion{}).ConProveedorDeTrazas(captura)
+			manejador := servidor.MiddlewareTrazas(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+			peticion := httptest.NewRequest(http.MethodGet, "/salud", nil)
+			if caso.traceparent != "" {
+				peticion.Header.Set("traceparent", caso.traceparent)
+			}
+			manejador.ServeHTTP(httptest.NewRecorder(), peticion)
+
+			if len(captura.spans) != 1 {
+				t.Fatalf("se esperaba exactamente un span, hay %d", len(captura.spans))
+			}
+			span := captura.spans[0]
+			if len(span.Traza.IDTraza) != 32 || span.Traza.IDTraza == "4bf92f3577b34da6a3ce929d0e0e4736" {
+				t.Fatalf("debería estrenarse un trace ID propio: %q", span.Traza.IDTraza)
+			}
+			if span.IDPadre != "" {
+				t.Fatalf("una traza nueva no tiene span padre: %q", span.IDPadre)
+			}
+		})
+	}
+}
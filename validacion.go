@@ -0,0 +1,175 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net"
+	"net/mail"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ReglasValidacion agrupa las reglas de validación de usuarios que
+// varían entre despliegues. Se inyectan con ConReglasValidacion; sin
+// eso rigen las de reglasValidacionPorDefecto, que reproducen el
+// comportamiento de siempre.
+type ReglasValidacion struct {
+	// EmailUnico rechaza con 409 los emails ya registrados. Ojo:
+	// desactivarlo solo relaja la comprobación de los handlers; un
+	// backend con índice único propio (SQLite) seguirá rechazando.
+	EmailUnico bool
+
+	// DominiosPermitidos, si no está vacía, restringe los emails a esos
+	// dominios, sin distinguir mayúsculas. Vacía admite cualquiera.
+	DominiosPermitidos []string
+
+	// LongitudMaximaNombre acota los caracteres del nombre; con cero se
+	// hereda Configuracion.LongitudMaximaNombre o el valor por defecto.
+	LongitudMaximaNombre int
+}
+
+// reglasValidacionPorDefecto son las reglas efectivas cuando nadie
+// inyecta otras: email único, cualquier dominio, longitud heredada.
+func reglasValidacionPorDefecto() ReglasValidacion {
+	return ReglasValidacion{EmailUnico: true}
+}
+
+// ConReglasValidacion sustituye las reglas de validación por defecto.
+// Los dominios permitidos se normalizan a minúsculas al entrar.
+func (s *ServidorHTTP) ConReglasValidacion(reglas ReglasValidacion) *ServidorHTTP {
+	for i, dominio := range reglas.DominiosPermitidos {
+		reglas.DominiosPermitidos[i] = strings.ToLower(strings.TrimSpace(dominio))
+	}
+	s.reglas = reglas
+	return s
+}
+
+// dominioAdmitido indica si el dominio del email cae dentro de la lista
+// de permitidos; sin lista configurada cualquier dominio pasa.
+func (s *ServidorHTTP) dominioAdmitido(email string) bool {
+	if len(s.reglas.DominiosPermitidos) == 0 {
+		return true
+	}
+	dominio := dominioDeEmail(email)
+	for _, permitido := range s.reglas.DominiosPermitidos {
+		if dominio == permitido {
+			return true
+		}
+	}
+	return false
+}
+
+// validarEmail comprueba que el email tenga un formato razonable. Se apoya
+// en net/mail.ParseAddress y exige además un dominio con punto, ya que
+// direcciones como "a@b" son válidas para RFC 5322 pero inútiles aquí.
+func validarEmail(email string) bool {
+	direccion, err := mail.ParseAddress(email)
+	if err != nil || direccion.Address != email {
+		return false
+	}
+	return strings.Contains(dominioDeEmail(email), ".")
+}
+
+// dominioDeEmail devuelve el dominio del email en minúsculas, o cadena
+// vacía si el email no tiene forma usuario@dominio.
+func dominioDeEmail(email string) string {
+	arroba := strings.LastIndex(email, "@")
+	if arroba < 0 || arroba == len(email)-1 {
+		return ""
+	}
+	return strings.ToLower(email[arroba+1:])
+}
+
+// resolutorMX abstrae la consulta DNS de registros MX para poder simular
+// el resolver en pruebas.
+type resolutorMX interface {
+	LookupMX(ctx context.Context, dominio string) ([]*net.MX, error)
+}
+
+// verificadorMX comprueba que el dominio de un email tenga registros MX,
+// cacheando el resultado por dominio para no repetir consultas DNS.
+type verificadorMX struct {
+	resolutor    resolutorMX
+	tiempoEspera time.Duration
+	mutex        sync.Mutex
+	cache        map[string]bool
+}
+
+func nuevoVerificadorMX(resolutor resolutorMX) *verificadorMX {
+	if resolutor == nil {
+		resolutor = net.DefaultResolver
+	}
+	return &verificadorMX{
+		resolutor:    resolutor,
+		tiempoEspera: 3 * time.Second,
+		cache:        map[string]bool{},
+	}
+}
+
+// dominioTieneMX devuelve false solo cuando el DNS confirma que el dominio
+// no tiene registros MX. Los errores transitorios (timeout, red) no
+// bloquean la creación y no se cachean.
+func (v *verificadorMX) dominioTieneMX(ctx context.Context, dominio string) bool {
+	v.mutex.Lock()
+	if resultado, cacheado := v.cache[dominio]; cacheado {
+		v.mutex.Unlock()
+		return resultado
+	}
+	v.mutex.Unlock()
+
+	ctx, cancelar := context.WithTimeout(ctx, v.tiempoEspera)
+	defer cancelar()
+
+	registros, err := v.resolutor.LookupMX(ctx, dominio)
+	if err != nil {
+		var errDNS *net.DNSError
+		if errors.As(err, &errDNS) && errDNS.IsNotFound {
+			v.recordar(dominio, false)
+			return false
+		}
+		// Error transitorio: no castigamos al usuario por un DNS caído.
+		return true
+	}
+
+	tiene := len(registros) > 0
+	v.recordar(dominio, tiene)
+	return tiene
+}
+
+func (v *verificadorMX) recordar(dominio string, tiene bool) {
+	v.mutex.Lock()
+	v.cache[dominio] = tiene
+	v.mutex.Unlock()
+}
+
+// normalizarNombre reduce un nombre a su forma canónica para comparar
+// unicidad: sin espacios alrededor, espacios internos colapsados y en
+// minúsculas.
+func normalizarNombre(nombre string) string {
+	return strings.ToLower(strings.Join(strings.Fields(nombre), " "))
+}
+
+// cargarDominiosDesechables lee un archivo con un dominio por línea y lo
+// convierte en un set de búsqueda. Se ignoran líneas vacías y comentarios
+// que empiecen con #.
+func cargarDominiosDesechables(ruta string) (map[string]struct{}, error) {
+	archivo, err := os.Open(ruta)
+	if err != nil {
+		return nil, err
+	}
+	defer archivo.Close()
+
+	dominios := map[string]struct{}{}
+	escaner := bufio.NewScanner(archivo)
+	for escaner.Scan() {
+		linea := strings.TrimSpace(escaner.Text())
+		if linea == "" || strings.HasPrefix(linea, "#") {
+			continue
+		}
+		dominios[strings.ToLower(linea)] = struct{}{}
+	}
+	return dominios, escaner.Err()
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestBloqueoDeEmailsDesechables(t *testing.T) {
+	ruta := filepath.Join(t.TempDir(), "desechables.txt")
+	contenido := "# dominios bloqueados\nmailinator.com\nCORREOTEMPORAL.ES\n\n"
+	if err := os.WriteFile(ruta, []byte(contenido), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	servidor := NuevoServidor(&Configuracion{
+		BloquearDesechables:     true,
+		RutaDominiosDesechables: ruta,
+	})
+
+	crear := func(email string) *httptest.ResponseRecorder {
+		cuerpo := strings.NewReader(`{"nombre":"Ana","email":"` + email + `"}`)
+		peticion := httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo)
+		grabadora := httptest.NewRecorder()
+		servidor.crearUsuario(grabadora, peticion)
+		return grabadora
+	}
+
+	if codigo := crear("ana@mailinator.com").Code; codigo != http.StatusUnprocessableEntity {
+		t.Fatalf("dominio desechable debería dar 422, dio %d", codigo)
+	}
+	if codigo := crear("ana@correotemporal.es").Code; codigo != http.StatusUnprocessableEntity {
+		t.Fatalf("la comparación debe ignorar mayúsculas, dio %d", codigo)
+	}
+	if codigo := crear("ana@empresa.com").Code; codigo != http.StatusCreated {
+		t.Fatalf("dominio normal debería dar 201, dio %d", codigo)
+	}
+}
+
+// resolutorSimulado responde con MX solo para los dominios listados y
+// cuenta las consultas realizadas.
+type resolutorSimulado struct {
+	conMX     map[string]bool
+	consultas int
+}
+
+func (r *resolutorSimulado) LookupMX(ctx context.Context, dominio string) ([]*net.MX, error) {
+	r.consultas++
+	if r.conMX[dominio] {
+		return []*net.MX{{Host: "mx." + dominio}}, nil
+	}
+	return nil, &net.DNSError{Err: "no such host", Name: dominio, IsNotFound: true}
+}
+
+func TestVerificacionMXAlCrearUsuario(t *testing.T) {
+	resolutor := &resolutorSimulado{conMX: map[string]bool{"empresa.com": true}}
+	servidor := NuevoServidor(&Configuracion{VerificarMX: true})
+	servidor.verificadorEmailMX = nuevoVerificadorMX(resolutor)
+
+	crear := func(email string) int {
+		cuerpo := strings.NewReader(`{"nombre":"Ana","email":"` + email + `"}`)
+		grabadora := httptest.NewRecorder()
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+		return grabadora.Code
+	}
+
+	if codigo := crear("ana@empresa.com"); codigo != http.StatusCreated {
+		t.Fatalf("dominio con MX debería dar 201, dio %d", codigo)
+	}
+	if codigo := crear("ana@sin-correo.com"); codigo != http.StatusUnprocessableEntity {
+		t.Fatalf("dominio sin MX debería dar 422, dio %d", codigo)
+	}
+
+	// El resultado se cachea por dominio: repetir no consulta de nuevo.
+	consultas := resolutor.consultas
+	crear("otra@empresa.com")
+	crear("otra@sin-correo.com")
+	if resolutor.consultas != consultas {
+		t.Fatalf("se esperaba usar la caché, hubo %d consultas extra", resolutor.consultas-consultas)
+	}
+}
+
+func TestUnicidadDeNombreOpcional(t *testing.T) {
+	contador := 0
+	crear := func(servidor *ServidorHTTP, nombre string) int {
+		contador++
+		cuerpo := strings.NewReader(fmt.Sprintf(`{"nombre":"%s","email":"usuario%d@ejemplo.com"}`, nombre, contador))
+		grabadora := httptest.NewRecorder()
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+		return grabadora.Code
+	}
+
+	t.Run("con NombreUnico rechaza duplicados normalizados", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{NombreUnico: true})
+		if codigo := crear(servidor, "Ana"); codigo != http.StatusCreated {
+			t.Fatalf("primera creación debería dar 201, dio %d", codigo)
+		}
+		if codigo := crear(servidor, "  ANA "); codigo != http.StatusConflict {
+			t.Fatalf("duplicado normalizado debería dar 409, dio %d", codigo)
+		}
+	})
+
+	t.Run("sin NombreUnico permite duplicados", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		crear(servidor, "Ana")
+		if codigo := crear(servidor, "Ana"); codigo != http.StatusCreated {
+			t.Fatalf("sin el flag debería permitir el duplicado, dio %d", codigo)
+		}
+	})
+}
+
+func TestRechazarEmailsDuplicados(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+
+	crear := func(nombre, email string) *httptest.ResponseRecorder {
+		cuerpo := strings.NewReader(`{"nombre":"` + nombre + `","email":"` + email + `"}`)
+		grabadora := httptest.NewRecorder()
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+		return grabadora
+	}
+
+	if codigo := crear("Ana", "ana@ejemplo.com").Code; codigo != http.StatusCreated {
+		t.Fatalf("primera creación debería dar 201, dio %d", codigo)
+	}
+
+	segunda := crear("Otra Ana", "ANA@Ejemplo.com")
+	if segunda.Code != http.StatusConflict {
+		t.Fatalf("email duplicado (case-insensitive) debería dar 409, dio %d", segunda.Code)
+	}
+	if !strings.Contains(segunda.Body.String(), "Ya existe un usuario con el email") {
+		t.Fatalf("falta el mensaje explicativo: %s", segunda.Body.String())
+	}
+}
+
+func TestValidarEmail(t *testing.T) {
+	casos := []struct {
+		email  string
+		valido bool
+	}{
+		{"ana@ejemplo.com", true},
+		{"ana.perez+etiqueta@sub.ejemplo.com", true},
+		{"no-es-un-email", false},
+		{"falta@dominio", false},
+		{"Ana <ana@ejemplo.com>", false},
+		{"@ejemplo.com", false},
+		{"", false},
+	}
+	for _, caso := range casos {
+		if valido := validarEmail(caso.email); valido != caso.valido {
+			t.Errorf("validarEmail(%q) = %v, esperado %v", caso.email, valido, caso.valido)
+		}
+	}
+}
+
+func TestCrearUsuarioRechazaEmailInvalido(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	cuerpo := strings.NewReader(`{"nombre":"Ana","email":"no-es-un-email"}`)
+	grabadora := httptest.NewRecorder()
+	servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+	if grabadora.Code != http.StatusBadRequest {
+		t.Fatalf("código esperado 400, obtenido %d", grabadora.Code)
+	}
+	if !strings.Contains(grabadora.Body.String(), "formato válido") {
+		t.Fatalf("mensaje inesperado: %s", grabadora.Body.String())
+	}
+}
+
+func TestDominioDeEmail(t *testing.T) {
+	casos := []struct {
+		email   string
+		dominio string
+	}{
+		{"ana@Ejemplo.COM", "ejemplo.com"},
+		{"sin-arroba", ""},
+		{"termina@", ""},
+	}
+	for _, caso := range casos {
+		if dominio := dominioDeEmail(caso.email); dominio != caso.dominio {
+			t.Errorf("dominioDeEmail(%q) = %q, esperado %q", caso.email, dominio, caso.dominio)
+		}
+	}
+}
+
+func TestReglasDeValidacionInyectables(t *testing.T) {
+	crear := func(servidor *ServidorHTTP, nombre, email string) *httptest.ResponseRecorder {
+		cuerpo := strings.NewReader(fmt.Sprintf(`{"nombre":"%s","email":"%s"}`, nombre, email))
+		grabadora := httptest.NewRecorder()
+		servidor.crearUsuario(grabadora, httptest.NewRequest(http.MethodPost, "/usuarios", cuerpo))
+		return grabadora
+	}
+
+	t.Run("dominios permitidos rechazan el resto con 400", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{}).ConReglasValidacion(ReglasValidacion{
+			EmailUnico:         true,
+			DominiosPermitidos: []string{"Empresa.com", "filial.es"},
+		})
+
+		grabadora := crear(servidor, "Ana", "ana@externo.net")
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("dominio fuera de la lista debería dar 400, dio %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), "externo.net") || !strings.Contains(grabadora.Body.String(), CodigoEmailInvalido) {
+			t.Fatalf("el error debería nombrar el dominio rechazado: %s", grabadora.Body.String())
+		}
+		// La comparación ignora mayúsculas en ambos lados.
+		if codigo := crear(servidor, "Ana", "ana@EMPRESA.com").Code; codigo != http.StatusCreated {
+			t.Fatalf("dominio permitido debería dar 201, dio %d", codigo)
+		}
+	})
+
+	t.Run("los dominios permitidos también rigen en el PATCH", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{}).ConReglasValidacion(ReglasValidacion{
+			EmailUnico:         true,
+			DominiosPermitidos: []string{"empresa.com"},
+		})
+		usuario := crearUsuarioDePrueba(t, servidor, "Ana", "ana@empresa.com")
+
+		peticion := httptest.NewRequest(http.MethodPatch, fmt.Sprintf("/usuarios/%d", usuario.ID),
+			strings.NewReader(`{"email":"ana@externo.net"}`))
+		grabadora := httptest.NewRecorder()
+		servidor.actualizarUsuarioParcial(grabadora, peticion, usuario.ID)
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("el PATCH a un dominio no permitido debería dar 400, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("sin EmailUnico se permiten emails repetidos", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{}).ConReglasValidacion(ReglasValidacion{})
+		if codigo := crear(servidor, "Ana", "ana@ejemplo.com").Code; codigo != http.StatusCreated {
+			t.Fatalf("primera creación debería dar 201, dio %d", codigo)
+		}
+		if codigo := crear(servidor, "Ana bis", "ana@ejemplo.com").Code; codigo != http.StatusCreated {
+			t.Fatalf("sin EmailUnico el repetido debería dar 201, dio %d", codigo)
+		}
+	})
+
+	t.Run("la longitud máxima de nombre de las reglas manda", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{}).ConReglasValidacion(ReglasValidacion{
+			EmailUnico:           true,
+			LongitudMaximaNombre: 5,
+		})
+		grabadora := crear(servidor, "Anabella", "larga@ejemplo.com")
+		if grabadora.Code != http.StatusBadRequest {
+			t.Fatalf("un nombre sobre el máximo debería dar 400, dio %d", grabadora.Code)
+		}
+		if !strings.Contains(grabadora.Body.String(), CodigoNombreInvalido) {
+			t.Fatalf("falta el código de error estable: %s", grabadora.Body.String())
+		}
+	})
+
+	t.Run("sin reglas inyectadas rige el comportamiento de siempre", func(t *testing.T) {
+		servidor := NuevoServidor(&Configuracion{})
+		crear(servidor, "Ana", "ana@cualquiera.org")
+		if codigo := crear(servidor, "Otra", "ana@cualquiera.org").Code; codigo != http.StatusConflict {
+			t.Fatalf("por defecto el email repetido debería dar 409, dio %d", codigo)
+		}
+	})
+}
@@ -0,0 +1,41 @@
+package main
+
+import "net/http"
+
+// Información de build inyectada al compilar, pensada para que soporte
+// identifique exactamente qué binario corre en producción:
+//
+//	go build -ldflags "-X main.Version=1.4.0 -X main.Commit=$(git rev-parse --short HEAD) -X main.FechaBuild=$(date -u +%Y-%m-%dT%H:%M:%SZ)"
+//
+// Un build local sin -ldflags se identifica con los valores "dev".
+var (
+	Version    = "dev"
+	Commit     = "dev"
+	FechaBuild = "dev"
+)
+
+// datosVersion arma el bloque de build que comparten /version y la raíz.
+func datosVersion() map[string]interface{} {
+	return map[string]interface{}{
+		"version":     Version,
+		"commit":      Commit,
+		"fecha_build": FechaBuild,
+	}
+}
+
+// manejarVersion expone la versión semántica, el commit y la fecha de
+// build del binario en ejecución.
+func (s *ServidorHTTP) manejarVersion(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		enviarRespuestaJSON(w, http.StatusMethodNotAllowed, RespuestaJSON{
+			Exitoso:     false,
+			Error:       "Método no permitido",
+			CodigoError: CodigoMetodoNoPermitido,
+		})
+		return
+	}
+	enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+		Exitoso: true,
+		Datos:   datosVersion(),
+	})
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEndpointDeVersion(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	rutas := servidor.configurarRutas()
+
+	t.Run("sin -ldflags responde los valores dev", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		rutas.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/version", nil))
+
+		if grabadora.Code != http.StatusOK {
+			t.Fatalf("/version debería dar 200, dio %d", grabadora.Code)
+		}
+		var respuesta struct {
+			Exitoso bool              `json:"exitoso"`
+			Datos   map[string]string `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if !respuesta.Exitoso {
+			t.Fatal("la respuesta de /version debería ser exitosa")
+		}
+		for _, campo := range []string{"version", "commit", "fecha_build"} {
+			if respuesta.Datos[campo] != "dev" {
+				t.Fatalf("sin inyección, %s debería ser \"dev\", llegó %q", campo, respuesta.Datos[campo])
+			}
+		}
+	})
+
+	t.Run("rechaza métodos distintos de GET", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		rutas.ServeHTTP(grabadora, httptest.NewRequest(http.MethodPost, "/version", nil))
+		if grabadora.Code != http.StatusMethodNotAllowed {
+			t.Fatalf("POST /version debería dar 405, dio %d", grabadora.Code)
+		}
+	})
+
+	t.Run("la raíz incluye el bloque de build", func(t *testing.T) {
+		grabadora := httptest.NewRecorder()
+		rutas.ServeHTTP(grabadora, httptest.NewRequest(http.MethodGet, "/", nil))
+
+		var respuesta struct {
+			Datos struct {
+				Build map[string]string `json:"build"`
+			} `json:"datos"`
+		}
+		if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+			t.Fatal(err)
+		}
+		if respuesta.Datos.Build["version"] != "dev" {
+			t.Fatalf("la raíz debería anunciar la versión, llegó %v", respuesta.Datos.Build)
+		}
+	})
+}
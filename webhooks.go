@@ -0,0 +1,121 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// eventoWebhook es el cuerpo que se envía a la URL de webhook configurada.
+type eventoWebhook struct {
+	Evento  string   `json:"evento"`
+	Usuario *Usuario `json:"usuario,omitempty"`
+	Enviado string   `json:"enviado"`
+}
+
+// enviarWebhook hace un POST síncrono del evento a la URL configurada y
+// devuelve el código de respuesta y la latencia observada.
+func (s *ServidorHTTP) enviarWebhook(evento eventoWebhook) (codigo int, latencia time.Duration, err error) {
+	cuerpo, err := json.Marshal(evento)
+	if err != nil {
+		return 0, 0, fmt.Errorf("error al serializar el evento: %w", err)
+	}
+
+	cliente := &http.Client{Timeout: 5 * time.Second}
+	inicio := time.Now()
+	respuesta, err := cliente.Post(s.configuracion.URLWebhook, "application/json", bytes.NewReader(cuerpo))
+	latencia = time.Since(inicio)
+	if err != nil {
+		return 0, latencia, err
+	}
+	respuesta.Body.Close()
+	return respuesta.StatusCode, latencia, nil
+}
+
+// pausaReintentoWebhook separa los dos intentos de entrega de un evento.
+const pausaReintentoWebhook = time.Second
+
+// notificarWebhook entrega un evento de usuario en segundo plano, para no
+// bloquear la respuesta al cliente, con un único reintento tras una
+// pausa. Sin URL configurada no hace nada; las entregas fallidas solo se
+// registran, el API nunca falla por culpa del webhook.
+func (s *ServidorHTTP) notificarWebhook(nombre string, usuario Usuario) {
+	if s.configuracion.URLWebhook == "" {
+		return
+	}
+	evento := eventoWebhook{
+		Evento:  nombre,
+		Usuario: &usuario,
+		Enviado: time.Now().Format(time.RFC3339),
+	}
+	irSeguro(func() {
+		for intento := 1; intento <= 2; intento++ {
+			codigo, latencia, err := s.enviarWebhook(evento)
+			if err == nil && codigo < http.StatusBadRequest {
+				s.registrador.Debug("webhook entregado",
+					"evento", nombre, "codigo", codigo,
+					"latencia_ms", latencia.Milliseconds(), "intento", intento)
+				return
+			}
+			if err != nil {
+				s.registrador.Warn("fallo al entregar el webhook",
+					"evento", nombre, "intento", intento, "error", err)
+			} else {
+				s.registrador.Warn("el webhook respondió con error",
+					"evento", nombre, "intento", intento, "codigo", codigo)
+			}
+			if intento == 1 {
+				time.Sleep(pausaReintentoWebhook)
+			}
+		}
+	})
+}
+
+// manejarProbarWebhook envía un evento de prueba a la URL de webhook
+// configurada y devuelve el resultado (código, latencia, error) sin
+// necesidad de crear un usuario real.
+func (s *ServidorHTTP) manejarProbarWebhook(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		enviarRespuestaJSON(w, http.StatusMethodNotAllowed, RespuestaJSON{
+			Exitoso: false,
+			Error:   "Método no permitido",
+		})
+		return
+	}
+
+	if s.configuracion.URLWebhook == "" {
+		enviarRespuestaJSON(w, http.StatusBadRequest, RespuestaJSON{
+			Exitoso: false,
+			Error:   "No hay URL de webhook configurada",
+		})
+		return
+	}
+
+	codigo, latencia, err := s.enviarWebhook(eventoWebhook{
+		Evento:  "prueba",
+		Enviado: time.Now().Format(time.RFC3339),
+	})
+
+	resultado := map[string]interface{}{
+		"url":         s.configuracion.URLWebhook,
+		"codigo":      codigo,
+		"latencia_ms": latencia.Milliseconds(),
+	}
+	if err != nil {
+		resultado["error"] = err.Error()
+		enviarRespuestaJSON(w, http.StatusBadGateway, RespuestaJSON{
+			Exitoso: false,
+			Error:   "El webhook no respondió correctamente",
+			Datos:   resultado,
+		})
+		return
+	}
+
+	enviarRespuestaJSON(w, http.StatusOK, RespuestaJSON{
+		Exitoso: true,
+		Mensaje: "Evento de prueba enviado",
+		Datos:   resultado,
+	})
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestProbarWebhookManualmente(t *testing.T) {
+	var recibido eventoWebhook
+	receptor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cuerpo, _ := io.ReadAll(r.Body)
+		json.Unmarshal(cuerpo, &recibido)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receptor.Close()
+
+	servidor := NuevoServidor(&Configuracion{URLWebhook: receptor.URL})
+	grabadora := httptest.NewRecorder()
+	servidor.manejarProbarWebhook(grabadora, httptest.NewRequest(http.MethodPost, "/admin/webhooks/probar", nil))
+
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("código esperado 200, obtenido %d: %s", grabadora.Code, grabadora.Body.String())
+	}
+	if recibido.Evento != "prueba" {
+		t.Fatalf("el receptor debería recibir un evento de prueba, recibió %+v", recibido)
+	}
+
+	var respuesta struct {
+		Datos struct {
+			Codigo     int   `json:"codigo"`
+			LatenciaMS int64 `json:"latencia_ms"`
+		} `json:"datos"`
+	}
+	if err := json.Unmarshal(grabadora.Body.Bytes(), &respuesta); err != nil {
+		t.Fatal(err)
+	}
+	if respuesta.Datos.Codigo != http.StatusNoContent {
+		t.Fatalf("el resultado debería reportar el código del receptor, reportó %d", respuesta.Datos.Codigo)
+	}
+}
+
+func TestWebhookNotificaCreacionYEliminacion(t *testing.T) {
+	eventos := make(chan eventoWebhook, 2)
+	receptor := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var evento eventoWebhook
+		cuerpo, _ := io.ReadAll(r.Body)
+		json.Unmarshal(cuerpo, &evento)
+		eventos <- evento
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer receptor.Close()
+
+	esperarEvento := func(t *testing.T) eventoWebhook {
+		t.Helper()
+		select {
+		case evento := <-eventos:
+			return evento
+		case <-time.After(2 * time.Second):
+			t.Fatal("el receptor no recibió ningún evento")
+			return eventoWebhook{}
+		}
+	}
+
+	servidor := NuevoServidor(&Configuracion{URLWebhook: receptor.URL})
+	creado := crearUsuarioDePrueba(t, servidor, "Ana", "ana@ejemplo.com")
+
+	evento := esperarEvento(t)
+	if evento.Evento != "usuario.creado" {
+		t.Fatalf("evento esperado usuario.creado, fue %q", evento.Evento)
+	}
+	if evento.Usuario == nil || evento.Usuario.Email != "ana@ejemplo.com" {
+		t.Fatalf("el evento debería llevar el usuario creado: %+v", evento.Usuario)
+	}
+
+	grabadora := httptest.NewRecorder()
+	servidor.eliminarUsuario(grabadora, httptest.NewRequest(http.MethodDelete, "/usuarios/1", nil), creado.ID)
+	if grabadora.Code != http.StatusOK {
+		t.Fatalf("la eliminación debería dar 200, dio %d: %s", grabadora.Code, grabadora.Body.String())
+	}
+
+	evento = esperarEvento(t)
+	if evento.Evento != "usuario.eliminado" {
+		t.Fatalf("evento esperado usuario.eliminado, fue %q", evento.Evento)
+	}
+	if evento.Usuario == nil || evento.Usuario.ID != creado.ID {
+		t.Fatalf("el evento debería llevar el usuario eliminado: %+v", evento.Usuario)
+	}
+}
+
+func TestProbarWebhookSinConfigurar(t *testing.T) {
+	servidor := NuevoServidor(&Configuracion{})
+	grabadora := httptest.NewRecorder()
+	servidor.manejarProbarWebhook(grabadora, httptest.NewRequest(http.MethodPost, "/admin/webhooks/probar", nil))
+	if grabadora.Code != http.StatusBadRequest {
+		t.Fatalf("sin URL configurada debería dar 400, dio %d", grabadora.Code)
+	}
+}